@@ -0,0 +1,47 @@
+package shellyctl
+
+import (
+	"sync"
+	"time"
+)
+
+// responseCache holds device responses that rarely change keyed by request URL, shared by all plugs
+var responseCache = &ttlCache{entries: map[string]cacheEntry{}}
+
+type cacheEntry struct {
+	body    []byte
+	expires time.Time
+}
+
+// ttlCache is a cache where every entry expires after a set time, safe for concurrent use
+type ttlCache struct {
+	entries map[string]cacheEntry
+	mu      sync.Mutex
+}
+
+func (c *ttlCache) get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	if time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	return entry.body, true
+}
+
+func (c *ttlCache) set(key string, body []byte, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	c.entries[key] = cacheEntry{body: body, expires: time.Now().Add(ttl)}
+	c.mu.Unlock()
+}
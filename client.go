@@ -0,0 +1,161 @@
+package shellyctl
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"golang.org/x/net/proxy"
+)
+
+// newRestyClient creates a HTTP client configured for communicating with the device at address
+func newRestyClient(address *url.URL, cfg plugConfig) (*resty.Client, error) {
+	rc := resty.New()
+	rc.SetTimeout(cfg.timeout)
+
+	tlsc := cfg.tlsConfig
+	if cfg.insecure {
+		if tlsc == nil {
+			tlsc = &tls.Config{}
+		}
+		tlsc = tlsc.Clone()
+		tlsc.InsecureSkipVerify = true
+		tlsc.VerifyPeerCertificate = nil
+	}
+	if tlsc != nil {
+		rc.SetTLSClientConfig(tlsc)
+	}
+
+	if cfg.retries > 0 {
+		rc.SetRetryCount(cfg.retries)
+		rc.SetRetryWaitTime(cfg.retryWait)
+		rc.SetRetryMaxWaitTime(cfg.retryWait)
+	}
+
+	rc.SetDebug(cfg.debug)
+
+	if cfg.userAgent != "" {
+		rc.SetHeader("User-Agent", cfg.userAgent)
+	}
+
+	if cfg.socksProxy != "" {
+		transport, err := rc.Transport()
+		if err != nil {
+			return nil, err
+		}
+
+		dialer, err := newDialer(cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		transport.Proxy = nil
+		transport.DialContext = dialer.DialContext
+	}
+
+	user, pass := cfg.user, cfg.pass
+	if user == "" && address.User != nil {
+		user = address.User.Username()
+		pass, _ = address.User.Password()
+	}
+
+	if user != "" {
+		rc.SetBasicAuth(user, pass)
+		rc.SetDisableWarn(true)
+
+		// Gen 2 devices reject Basic authentication with a Digest challenge
+		rc.SetTransport(&digestTransport{user: user, pass: pass, transport: rc.GetClient().Transport})
+	}
+
+	return rc, nil
+}
+
+// NewDialer creates a dialer that connects to devices like a Plug created using the same options does, connections
+// are made through the proxy set using WithSOCKS5Proxy and time out after the WithTimeout duration
+func NewDialer(opts ...Option) (proxy.ContextDialer, error) {
+	cfg := plugConfig{timeout: 10 * time.Second}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return newDialer(cfg)
+}
+
+func newDialer(cfg plugConfig) (proxy.ContextDialer, error) {
+	direct := &net.Dialer{Timeout: cfg.timeout}
+	if cfg.socksProxy == "" {
+		return direct, nil
+	}
+
+	dialer, err := proxy.SOCKS5("tcp", cfg.socksProxy, nil, direct)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SOCKS5 proxy: %v", err)
+	}
+
+	cd, ok := dialer.(proxy.ContextDialer)
+	if !ok {
+		return nil, fmt.Errorf("SOCKS5 proxy does not support contexts")
+	}
+
+	return cd, nil
+}
+
+// requestError describes why a request failed, certificates that could not be verified are reported with the
+// reason rather than as the device being unreachable
+func requestError(err error) error {
+	reason := certificateError(err)
+	if reason != "" {
+		return fmt.Errorf("%w: %s", ErrCertificateInvalid, reason)
+	}
+
+	return fmt.Errorf("%w: %v", ErrDeviceUnreachable, err)
+}
+
+// certificateError explains why the certificate of the device was rejected, empty when err is not a certificate
+// verification failure
+func certificateError(err error) string {
+	var hostname x509.HostnameError
+	var unknown x509.UnknownAuthorityError
+	var invalid x509.CertificateInvalidError
+
+	switch {
+	case errors.As(err, &hostname):
+		names := append([]string{}, hostname.Certificate.DNSNames...)
+		for _, ip := range hostname.Certificate.IPAddresses {
+			names = append(names, ip.String())
+		}
+		if len(names) == 0 {
+			names = append(names, hostname.Certificate.Subject.CommonName)
+		}
+
+		return fmt.Sprintf("hostname mismatch, the certificate is valid for %s and not %s", strings.Join(names, ", "), hostname.Host)
+
+	case errors.As(err, &unknown):
+		if unknown.Cert != nil {
+			return fmt.Sprintf("unknown certificate authority, the certificate was issued by %q which is not trusted", unknown.Cert.Issuer.String())
+		}
+
+		return "unknown certificate authority"
+
+	case errors.As(err, &invalid) && invalid.Reason == x509.Expired:
+		if invalid.Cert != nil && time.Now().Before(invalid.Cert.NotBefore) {
+			return fmt.Sprintf("certificate is not valid before %s", invalid.Cert.NotBefore.Format(time.RFC3339))
+		}
+		if invalid.Cert != nil {
+			return fmt.Sprintf("certificate expired on %s", invalid.Cert.NotAfter.Format(time.RFC3339))
+		}
+
+		return "certificate expired"
+
+	case errors.As(err, &invalid):
+		return invalid.Error()
+	}
+
+	return ""
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/choria-io/fisk"
+	"github.com/ripienaar/shellyctl"
+)
+
+var (
+	actionEvent string
+	actionURL   string
+
+	// gen1ActionEvents maps event names to the URL action names used by Gen 1 devices, other
+	// actions are named after the device action without the _url suffix
+	gen1ActionEvents = map[string]string{
+		"relay_on":   "out_on_url",
+		"relay_off":  "out_off_url",
+		"button_on":  "btn_on_url",
+		"button_off": "btn_off_url",
+	}
+)
+
+// gen1ActionName is the device action name for event
+func gen1ActionName(event string) string {
+	if name, ok := gen1ActionEvents[event]; ok {
+		return name
+	}
+
+	return strings.TrimSuffix(event, "_url") + "_url"
+}
+
+// gen1ActionEvent is the event name shown for the device action name
+func gen1ActionEvent(name string) string {
+	for event, n := range gen1ActionEvents {
+		if n == name {
+			return event
+		}
+	}
+
+	return strings.TrimSuffix(name, "_url")
+}
+
+// gen1DeviceActions retrieves the URL actions of a Gen 1 device
+func gen1DeviceActions(ctx context.Context, plug shellyctl.Plug) (map[string][]shellyctl.Gen1Action, error) {
+	_, err := requireGen1(ctx, plug)
+	if err != nil {
+		return nil, err
+	}
+
+	var res shellyctl.Gen1ActionSettings
+	err = plug.Get(ctx, "settings/actions", &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Actions, nil
+}
+
+func actionsListAction(_ *fisk.ParseContext) error {
+	return forEachDevice(actionsListDevice)
+}
+
+func actionsListDevice(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+	actions, err := gen1DeviceActions(ctx, plug)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "URL actions for %s\n", address)
+	fmt.Fprintln(w)
+
+	for _, name := range sortedKeys(actions) {
+		var urls []string
+		for _, action := range actions[name] {
+			for _, u := range action.URLs {
+				if u == "" {
+					continue
+				}
+
+				if !action.Enabled {
+					u += " (disabled)"
+				}
+				urls = append(urls, u)
+			}
+		}
+
+		if len(urls) == 0 {
+			fmt.Fprintf(w, "%20s: not set\n", gen1ActionEvent(name))
+			continue
+		}
+
+		for i, u := range urls {
+			event := gen1ActionEvent(name)
+			if i > 0 {
+				event = ""
+			}
+			fmt.Fprintf(w, "%20s: %s\n", event, u)
+		}
+	}
+
+	return nil
+}
+
+func actionsSetAction(_ *fisk.ParseContext) error {
+	return forEachDevice(func(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+		return actionsUpdateDevice(ctx, address, plug, actionURL, w)
+	})
+}
+
+func actionsDeleteAction(_ *fisk.ParseContext) error {
+	return forEachDevice(func(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+		return actionsUpdateDevice(ctx, address, plug, "", w)
+	})
+}
+
+// actionsUpdateDevice sets the URL of the action for --event, an empty url clears and disables the action
+func actionsUpdateDevice(ctx context.Context, address string, plug shellyctl.Plug, url string, w io.Writer) error {
+	actions, err := gen1DeviceActions(ctx, plug)
+	if err != nil {
+		return err
+	}
+
+	name := gen1ActionName(actionEvent)
+	if _, ok := actions[name]; !ok {
+		var events []string
+		for _, n := range sortedKeys(actions) {
+			events = append(events, gen1ActionEvent(n))
+		}
+
+		return fmt.Errorf("%s does not support the %s event, supported events: %s", address, actionEvent, strings.Join(events, ", "))
+	}
+
+	_, err = plug.UpdateSettings(ctx, "actions", map[string]string{
+		"index":   "0",
+		"name":    name,
+		"enabled": fmt.Sprint(url != ""),
+		"urls[]":  url,
+	})
+	if err != nil {
+		return err
+	}
+
+	if url == "" {
+		fmt.Fprintf(w, "Removed the %s action on %s\n", actionEvent, address)
+	} else {
+		fmt.Fprintf(w, "Set the %s action on %s to %s\n", actionEvent, address, url)
+	}
+
+	return nil
+}
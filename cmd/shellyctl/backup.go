@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/choria-io/fisk"
+	"github.com/ripienaar/shellyctl"
+)
+
+var (
+	backupFile  string
+	restoreFile string
+
+	// gen1ReadOnlySettings are settings reported by /settings that cannot be restored
+	gen1ReadOnlySettings = map[string]bool{"fw": true, "time": true, "unixtime": true, "build_info": true, "hwinfo": true, "device": true}
+
+	// gen1ReadOnlyRelaySettings are relay settings reported by /settings that cannot be restored
+	gen1ReadOnlyRelaySettings = map[string]bool{"ison": true, "has_timer": true, "overpower": true, "is_valid": true}
+
+	// gen2SkipRestore are components that are not restored, backups do not include WiFi passwords
+	gen2SkipRestore = map[string]bool{"wifi": true}
+)
+
+func backupAction(_ *fisk.ParseContext) error {
+	if backupFile != "" && len(addresses) > 1 {
+		return fmt.Errorf("only a single device can be backed up to a file")
+	}
+
+	return forEachDevice(backupDevice)
+}
+
+// deviceConfig retrieves the configuration of a device, /settings for Gen 1 and Shelly.GetConfig for Gen 2
+func deviceConfig(ctx context.Context, plug shellyctl.Plug) (map[string]any, error) {
+	nfo, err := plug.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg map[string]any
+	if nfo.Generation() == 1 {
+		cfg, err = plug.Settings(ctx)
+	} else {
+		err = plug.RPC(ctx, "Shelly.GetConfig", nil, &cfg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+func backupDevice(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+	cfg, err := deviceConfig(ctx, plug)
+	if err != nil {
+		return err
+	}
+
+	j, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if backupFile == "" {
+		fmt.Fprintln(w, string(j))
+		return nil
+	}
+
+	err = os.WriteFile(backupFile, append(j, '\n'), 0600)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Saved configuration of %s to %s\n", address, backupFile)
+
+	return nil
+}
+
+func restoreAction(_ *fisk.ParseContext) error {
+	return forEachDevice(restoreDevice)
+}
+
+func restoreDevice(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+	cfgj, err := os.ReadFile(restoreFile)
+	if err != nil {
+		return err
+	}
+
+	cfg := map[string]any{}
+	err = json.Unmarshal(cfgj, &cfg)
+	if err != nil {
+		return fmt.Errorf("invalid configuration in %s: %v", restoreFile, err)
+	}
+
+	nfo, err := plug.Info(ctx)
+	if err != nil {
+		return err
+	}
+
+	if nfo.Generation() == 1 {
+		err = restoreGen1(ctx, plug, cfg)
+	} else {
+		err = restoreGen2(ctx, plug, cfg, w)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Restored configuration of %s from %s\n", address, restoreFile)
+
+	return nil
+}
+
+func restoreGen1(ctx context.Context, plug shellyctl.Plug, cfg map[string]any) error {
+	settings := gen1SettingValues(cfg, gen1ReadOnlySettings)
+	if len(settings) > 0 {
+		_, err := plug.UpdateSettings(ctx, "", settings)
+		if err != nil {
+			return err
+		}
+	}
+
+	relays, ok := cfg["relays"].([]any)
+	if !ok {
+		return nil
+	}
+
+	for i, r := range relays {
+		relay, ok := r.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		_, err := plug.UpdateSettings(ctx, fmt.Sprintf("relay/%d", i), gen1SettingValues(relay, gen1ReadOnlyRelaySettings))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func restoreGen2(ctx context.Context, plug shellyctl.Plug, cfg map[string]any, w io.Writer) error {
+	var keys []string
+	for k := range cfg {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	restart := false
+	for _, key := range keys {
+		if gen2SkipRestore[key] {
+			slog.Warn("Skipping component", "component", key)
+			continue
+		}
+
+		method, id, err := gen2ComponentMethod(key, "SetConfig")
+		if err != nil {
+			slog.Warn("Skipping component", "component", key, "error", err)
+			continue
+		}
+
+		component, ok := cfg[key].(map[string]any)
+		if !ok {
+			continue
+		}
+
+		// these are reported by the device but cannot be set
+		if device, ok := component["device"].(map[string]any); ok && key == "sys" {
+			delete(device, "mac")
+			delete(device, "fw_id")
+		}
+
+		params := map[string]any{"config": component}
+		if id >= 0 {
+			params["id"] = id
+		}
+
+		var res struct {
+			RestartRequired bool `json:"restart_required"`
+		}
+		err = plug.RPC(ctx, method, params, &res)
+		if err != nil {
+			return fmt.Errorf("%s failed: %v", method, err)
+		}
+
+		restart = restart || res.RestartRequired
+	}
+
+	if restart {
+		fmt.Fprintln(w, "The device must be restarted for all settings to take effect")
+	}
+
+	return nil
+}
+
+// gen1SettingValues converts settings into query parameters, only scalar values and lists of scalars are supported
+func gen1SettingValues(settings map[string]any, skip map[string]bool) map[string]string {
+	res := map[string]string{}
+
+	for k, v := range settings {
+		if skip[k] {
+			continue
+		}
+
+		val, ok := gen1SettingValue(v)
+		if ok {
+			res[k] = val
+		}
+	}
+
+	return res
+}
+
+func gen1SettingValue(v any) (string, bool) {
+	switch val := v.(type) {
+	case string:
+		return val, true
+	case bool:
+		return strconv.FormatBool(val), true
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64), true
+	case []any:
+		var parts []string
+		for _, i := range val {
+			s, ok := gen1SettingValue(i)
+			if !ok {
+				return "", false
+			}
+			parts = append(parts, s)
+		}
+		return strings.Join(parts, ","), true
+	default:
+		return "", false
+	}
+}
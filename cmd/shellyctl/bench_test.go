@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/ripienaar/shellyctl"
+)
+
+var benchInfoV1 = &shellyctl.DeviceInfo{Type: "SHPLG-S", MAC: "C45BBE6B0A21", FW: "20230913-112003/v1.14.0-gcb84623"}
+
+// benchmarkEnergy renders an energy reading of the device described by info using the output flags set by setup
+func benchmarkEnergy(b *testing.B, info *shellyctl.DeviceInfo, setup func()) {
+	resetOutputFlags(b)
+	setup()
+
+	plug := &fuzzPlug{
+		info: info,
+		status: &shellyctl.DeviceStatus{
+			MAC:    info.MAC,
+			Relays: []shellyctl.Relay{{IsOn: true, Source: "http"}},
+			Meters: []shellyctl.Meter{{Power: 42.17, IsValid: true, Counters: []float64{41.912, 42.301, 42.05}, Total: 1234567}},
+		},
+	}
+	address := "192.168.1.10"
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		err := energyDevice(context.Background(), address, plug, io.Discard)
+		if err != nil {
+			b.Fatalf("energy failed: %v", err)
+		}
+	}
+}
+
+func BenchmarkRenderEnergyV1Text(b *testing.B) {
+	benchmarkEnergy(b, benchInfoV1, func() {})
+}
+
+func BenchmarkRenderEnergyV1JSON(b *testing.B) {
+	benchmarkEnergy(b, benchInfoV1, func() { jsonFormat = true })
+}
+
+func BenchmarkRenderEnergyV1Choria(b *testing.B) {
+	benchmarkEnergy(b, benchInfoV1, func() { choriaFormat = true; labels = map[string]string{"room": "office"} })
+}
+
+// BenchmarkPlugStatus measures a status request to a device, connections to the device are reused
+func BenchmarkPlugStatus(b *testing.B) {
+	dev := &mockDevice{dir: "gen1"}
+	plug := dev.start(b, time.Second)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, err := plug.Status(context.Background())
+		if err != nil {
+			b.Fatalf("status failed: %v", err)
+		}
+	}
+}
+
+// BenchmarkPlugRPCV2 measures a Gen 2 RPC request to a device, connections to the device are reused
+func BenchmarkPlugRPCV2(b *testing.B) {
+	dev := &mockDevice{dir: "gen2"}
+	plug := dev.start(b, time.Second)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		res := map[string]any{}
+		err := plug.RPC(context.Background(), "Sys.GetConfig", nil, &res)
+		if err != nil {
+			b.Fatalf("rpc failed: %v", err)
+		}
+	}
+}
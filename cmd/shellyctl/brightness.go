@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/ripienaar/shellyctl"
+)
+
+var dimmerBrightness int
+
+// requireDimmer ensures that plug is a Shelly Dimmer
+func requireDimmer(ctx context.Context, plug shellyctl.Plug) (shellyctl.Dimmer, error) {
+	dimmer, ok := plug.(shellyctl.Dimmer)
+	if ok {
+		return dimmer, nil
+	}
+
+	nfo, err := plug.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, fmt.Errorf("this command requires a Shelly Dimmer, %s is not a dimmer", nfo.Type)
+}
+
+func brightnessGetAction(_ *fisk.ParseContext) error {
+	return forEachDevice(brightnessGetDevice)
+}
+
+func brightnessGetDevice(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+	dimmer, err := requireDimmer(ctx, plug)
+	if err != nil {
+		return err
+	}
+
+	light, err := dimmer.Light(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Light information for %s\n", address)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "        Power Status: %s\n", onOffString(light.IsOn))
+	fmt.Fprintf(w, "          Brightness: %d%%\n", light.Brightness)
+	fmt.Fprintf(w, "          Transition: %v\n", time.Duration(light.Transition)*time.Millisecond)
+
+	return nil
+}
+
+func brightnessSetAction(_ *fisk.ParseContext) error {
+	if dimmerBrightness < 0 || dimmerBrightness > 100 {
+		return fmt.Errorf("brightness must be between 0 and 100")
+	}
+
+	return forEachDevice(brightnessSetDevice)
+}
+
+func brightnessSetDevice(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+	dimmer, err := requireDimmer(ctx, plug)
+	if err != nil {
+		return err
+	}
+
+	_, err = dimmer.SetBrightness(ctx, dimmerBrightness)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Brightness on %s set to %d%%\n", address, dimmerBrightness)
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/choria-io/fisk"
+	"github.com/ripienaar/shellyctl"
+)
+
+var (
+	circuitBreakerThreshold int
+
+	// errCircuitOpen is the error of devices skipped by the circuit breaker, they count as unreachable
+	errCircuitOpen = fmt.Errorf("%w: circuit open", shellyctl.ErrDeviceUnreachable)
+)
+
+// breakerState is persisted between runs and tracks consecutive failures to reach devices by address
+type breakerState struct {
+	Failures map[string]int `json:"failures"`
+
+	mu sync.Mutex
+}
+
+func breakerStateFile() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".shellyctl-state.json"), nil
+}
+
+func loadBreakerState() (*breakerState, error) {
+	state := &breakerState{Failures: map[string]int{}}
+
+	file, err := breakerStateFile()
+	if err != nil {
+		return nil, err
+	}
+
+	sb, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(sb, state)
+	if err != nil {
+		return nil, fmt.Errorf("invalid state file %s: %v", file, err)
+	}
+	if state.Failures == nil {
+		state.Failures = map[string]int{}
+	}
+
+	return state, nil
+}
+
+func (s *breakerState) save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := breakerStateFile()
+	if err != nil {
+		return err
+	}
+
+	j, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(file, j, 0600)
+}
+
+// skip determines if address failed often enough that it should be skipped, the error describes why it is skipped
+func (s *breakerState) skip(address string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	failures := s.Failures[address]
+	if failures < circuitBreakerThreshold {
+		return nil
+	}
+
+	return fmt.Errorf("%w after %d failures, skipped until reset using circuit-breaker reset", errCircuitOpen, failures)
+}
+
+// record updates the failure count of address, only failures to reach the device are counted
+func (s *breakerState) record(address string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case err == nil:
+		delete(s.Failures, address)
+	case errors.Is(err, shellyctl.ErrDeviceUnreachable):
+		s.Failures[address]++
+	}
+}
+
+func circuitBreakerResetAction(_ *fisk.ParseContext) error {
+	if len(addresses) == 0 {
+		return errNoAddresses
+	}
+
+	state, err := loadBreakerState()
+	if err != nil {
+		return err
+	}
+
+	for _, address := range addresses {
+		delete(state.Failures, address)
+		slog.Info("Reset circuit breaker", "device", address)
+	}
+
+	err = state.save()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(output, "Reset the circuit breaker for %d devices\n", len(addresses))
+
+	return nil
+}
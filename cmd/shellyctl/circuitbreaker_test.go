@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ripienaar/shellyctl"
+)
+
+func TestCircuitBreakerSkipsDevices(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Cleanup(func() { circuitBreakerThreshold, addresses, commandErr = 0, nil, nil })
+
+	state := &breakerState{Failures: map[string]int{"192.168.1.10": 3}}
+	err := state.save()
+	if err != nil {
+		t.Fatalf("could not save state: %v", err)
+	}
+
+	circuitBreakerThreshold = 3
+	addresses = []string{"192.168.1.10"}
+
+	ran := false
+	err = forEachDevice(func(_ context.Context, _ string, _ shellyctl.Plug, _ io.Writer) error {
+		ran = true
+		return nil
+	})
+	if ran {
+		t.Fatalf("expected the device to be skipped")
+	}
+	if !errors.Is(err, errCircuitOpen) || !strings.Contains(err.Error(), "after 3 failures") {
+		t.Fatalf("expected the skipped device to fail: %v", err)
+	}
+	if code := exitCodeFor(commandErr); code != exitUnreachable {
+		t.Fatalf("expected the unreachable exit code got %d", code)
+	}
+}
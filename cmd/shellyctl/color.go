@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/choria-io/fisk"
+	"github.com/fatih/color"
+)
+
+var (
+	colorMode string
+	noColor   bool
+
+	colorGood = color.New(color.FgGreen)
+	colorBad  = color.New(color.FgRed)
+	colorWarn = color.New(color.FgYellow)
+)
+
+// configureColor sets up color output based on the --color and --no-color flags, in auto mode
+// color is only used when writing to a terminal and NO_COLOR is not set
+func configureColor(_ *fisk.ParseContext) error {
+	switch {
+	case noColor, os.Getenv("NO_COLOR") != "":
+		color.NoColor = true
+	case colorMode == "always":
+		color.NoColor = false
+	case colorMode == "never":
+		color.NoColor = true
+	case output != os.Stdout:
+		color.NoColor = true
+	}
+
+	return nil
+}
+
+// onOffString returns a colored On or Off string
+func onOffString(on bool) string {
+	if on {
+		return colorGood.Sprint("On")
+	}
+
+	return colorBad.Sprint("Off")
+}
+
+// stateBool returns a boolean string that is green when true and red when false
+func stateBool(v bool) string {
+	if v {
+		return colorGood.Sprint(v)
+	}
+
+	return colorBad.Sprint(v)
+}
+
+// warnBool returns a boolean string that is colored as a warning when true
+func warnBool(v bool) string {
+	if v {
+		return colorWarn.Sprint(v)
+	}
+
+	return fmt.Sprint(v)
+}
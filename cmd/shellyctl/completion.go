@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// completionPingTimeout is how long --success-url and --failure-url are given to respond
+const completionPingTimeout = 3 * time.Second
+
+var (
+	successURL string
+	failureURL string
+)
+
+// commandSucceeded reports the successful completion of the command to --success-url
+func commandSucceeded() {
+	postCompletion(successURL, "")
+}
+
+// commandFailed runs --on-error and reports the failure to --failure-url after the command failed with status
+func commandFailed(status int) {
+	msg := failureMessage(status)
+
+	runOnError(msg)
+	postCompletion(failureURL, msg)
+}
+
+// failureMessage is the error the command failed with, the exit status when it is not known
+func failureMessage(status int) string {
+	msg := errorOutput.message()
+	if msg == "" && commandErr != nil {
+		msg = commandErr.Error()
+	}
+	if msg == "" {
+		msg = fmt.Sprintf("exit status %d", status)
+	}
+
+	return msg
+}
+
+// postCompletion posts body to url, failures are only logged as monitoring must not cause the command to fail
+func postCompletion(url string, body string) {
+	if url == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), completionPingTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		slog.Warn("Could not report command completion", "url", url, "error", err)
+		return
+	}
+	req.Header.Set("Content-Type", "text/plain")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		slog.Warn("Could not report command completion", "url", url, "error", err)
+		return
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		slog.Warn("Could not report command completion", "url", url, "status", resp.Status)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCommandFailed(t *testing.T) {
+	t.Cleanup(func() { failureURL, errorOutput = "", &errorRecorder{} })
+
+	var posted string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		posted = r.Method + " " + r.URL.Path + " " + string(body)
+	}))
+	defer srv.Close()
+
+	failureURL = srv.URL + "/uuid/fail"
+
+	errorOutput = &errorRecorder{}
+	errorOutput.Write([]byte("shellyctl: error: device unreachable\n"))
+
+	commandFailed(1)
+
+	if posted != "POST /uuid/fail device unreachable" {
+		t.Fatalf("unexpected failure ping %q", posted)
+	}
+}
+
+func TestCommandSucceeded(t *testing.T) {
+	t.Cleanup(func() { successURL = "" })
+
+	var posted bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posted = r.Method == http.MethodPost
+	}))
+
+	successURL = srv.URL
+	commandSucceeded()
+	if !posted {
+		t.Fatalf("success was not reported")
+	}
+
+	// unreachable URLs are only logged
+	srv.Close()
+	commandSucceeded()
+}
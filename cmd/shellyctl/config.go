@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/choria-io/fisk"
+	"github.com/ripienaar/shellyctl"
+)
+
+var (
+	configComponent string
+	configID        int
+	configParams    string
+)
+
+func configGetAction(_ *fisk.ParseContext) error {
+	return forEachDevice(configGetDevice)
+}
+
+func configGetDevice(ctx context.Context, _ string, plug shellyctl.Plug, w io.Writer) error {
+	_, err := requireGen2(ctx, plug)
+	if err != nil {
+		return err
+	}
+
+	method, id, err := gen2ComponentMethod(gen2ComponentKey(configComponent, configID), "GetConfig")
+	if err != nil {
+		return err
+	}
+
+	params := map[string]any{}
+	if id >= 0 {
+		params["id"] = id
+	}
+
+	var res any
+	err = plug.RPC(ctx, method, params, &res)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, res)
+}
+
+func configSetAction(_ *fisk.ParseContext) error {
+	cfg := map[string]any{}
+	err := json.Unmarshal([]byte(configParams), &cfg)
+	if err != nil {
+		return fmt.Errorf("invalid params: %v", err)
+	}
+
+	return forEachDevice(func(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+		return configSetDevice(ctx, address, plug, cfg, w)
+	})
+}
+
+func configSetDevice(ctx context.Context, address string, plug shellyctl.Plug, cfg map[string]any, w io.Writer) error {
+	_, err := requireGen2(ctx, plug)
+	if err != nil {
+		return err
+	}
+
+	key := gen2ComponentKey(configComponent, configID)
+	method, id, err := gen2ComponentMethod(key, "SetConfig")
+	if err != nil {
+		return err
+	}
+
+	params := map[string]any{"config": cfg}
+	if id >= 0 {
+		params["id"] = id
+	}
+
+	var res struct {
+		RestartRequired bool `json:"restart_required"`
+	}
+	err = plug.RPC(ctx, method, params, &res)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Updated %s configuration on %s\n", key, address)
+	if res.RestartRequired {
+		fmt.Fprintln(w, "The device must be restarted for the change to take effect")
+	}
+
+	return nil
+}
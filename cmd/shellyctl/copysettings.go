@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/choria-io/fisk"
+	"github.com/ripienaar/shellyctl"
+)
+
+var (
+	copyFrom    string
+	copyTo      string
+	copyExclude []string
+
+	// copySkipSettings are settings specific to a device that are never copied, Gen 2 MQTT topics default to the
+	// device id and would make both devices publish to the same topics
+	copySkipSettings = map[string]bool{"cloud": true, "mqtt.client_id": true, "mqtt.topic_prefix": true}
+)
+
+func copySettingsAction(_ *fisk.ParseContext) error {
+	fingerprints, err := loadFingerprints()
+	if err != nil {
+		return err
+	}
+
+	from, err := newDevice(copyFrom, fingerprints, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", copyFrom, err)
+	}
+
+	// only the device being copied to is changed, the requests to it are shown in dry-run mode
+	var dryRunOut io.Writer
+	if dryRun {
+		dryRunOut = output
+	}
+
+	to, err := newDevice(copyTo, fingerprints, dryRunOut)
+	if err != nil {
+		return fmt.Errorf("%s: %w", copyTo, err)
+	}
+
+	return copySettings(ctx, from, to, output)
+}
+
+// copySettings applies the settings of from that differ on to, read-only and device specific settings like the MAC
+// address and cloud configuration are skipped along with settings matching --exclude
+func copySettings(ctx context.Context, from shellyctl.Plug, to shellyctl.Plug, w io.Writer) error {
+	fromInfo, err := from.Info(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", copyFrom, err)
+	}
+
+	toInfo, err := to.Info(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", copyTo, err)
+	}
+
+	if fromInfo.Generation() != toInfo.Generation() {
+		return fmt.Errorf("cannot copy settings from a Gen %d device to a Gen %d device", fromInfo.Generation(), toInfo.Generation())
+	}
+
+	desired, err := deviceConfig(ctx, from)
+	if err != nil {
+		return fmt.Errorf("%s: %w", copyFrom, err)
+	}
+
+	current, err := deviceConfig(ctx, to)
+	if err != nil {
+		return fmt.Errorf("%s: %w", copyTo, err)
+	}
+
+	var changes []importChange
+	if fromInfo.Generation() == 1 {
+		changes = gen1ImportChanges(current, desired)
+	} else {
+		changes = gen2ImportChanges(current, desired)
+	}
+	changes = copyChanges(changes, copyExclude)
+
+	if len(changes) == 0 {
+		fmt.Fprintf(w, "Configuration of %s matches %s\n", copyTo, copyFrom)
+		return nil
+	}
+
+	fmt.Fprintf(w, "Changes to %s:\n", copyTo)
+	fmt.Fprintln(w)
+	for _, change := range changes {
+		fmt.Fprintf(w, "  %s\n", change)
+	}
+	fmt.Fprintln(w)
+
+	if fromInfo.Generation() == 1 {
+		err = importGen1(ctx, to, changes)
+	} else {
+		err = importGen2(ctx, to, changes, w)
+	}
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		return nil
+	}
+
+	fmt.Fprintf(w, "Applied %d changes from %s\n", len(changes), copyFrom)
+
+	return nil
+}
+
+// copyChanges removes device specific changes and those matching exclude, exclusions match a whole component like
+// wifi or relay/0, a setting like name or a setting of a component like switch:0.name
+func copyChanges(changes []importChange, exclude []string) []importChange {
+	skip := map[string]bool{}
+	for k := range copySkipSettings {
+		skip[k] = true
+	}
+	for _, e := range exclude {
+		for _, field := range strings.Split(e, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				skip[field] = true
+			}
+		}
+	}
+
+	var res []importChange
+	for _, change := range changes {
+		if skip[change.key] || skip[change.component+"."+change.key] || (change.component != "" && skip[change.component]) {
+			continue
+		}
+
+		res = append(res, change)
+	}
+
+	return res
+}
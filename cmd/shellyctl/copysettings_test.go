@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ripienaar/shellyctl"
+)
+
+func TestCopySettings(t *testing.T) {
+	ctx := context.Background()
+
+	var plugs []shellyctl.Plug
+	var addresses []url.URL
+	for range 2 {
+		srv := httptest.NewServer(newSimulatedPlug())
+		defer srv.Close()
+
+		address := url.URL{Scheme: "http", Host: srv.Listener.Addr().String()}
+		plug, err := shellyctl.NewPlug(address)
+		if err != nil {
+			t.Fatalf("could not create plug: %v", err)
+		}
+		plugs = append(plugs, plug)
+		addresses = append(addresses, address)
+	}
+	from, to := plugs[0], plugs[1]
+
+	_, err := from.UpdateSettings(ctx, "", map[string]string{"name": "Kitchen", "max_power": "1800"})
+	if err != nil {
+		t.Fatalf("updating settings failed: %v", err)
+	}
+	_, err = from.UpdateSettings(ctx, "relay/0", map[string]string{"auto_off": "60"})
+	if err != nil {
+		t.Fatalf("updating settings failed: %v", err)
+	}
+
+	copyFrom, copyTo, copyExclude, dryRun = "from", "to", []string{"name"}, true
+	t.Cleanup(func() { copyExclude, dryRun = nil, false })
+
+	out := &bytes.Buffer{}
+	dryRunTo, err := shellyctl.NewPlug(addresses[1], shellyctl.WithDryRun(out))
+	if err != nil {
+		t.Fatalf("could not create plug: %v", err)
+	}
+
+	err = copySettings(ctx, from, dryRunTo, out)
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "DRY RUN: GET http://"+addresses[1].Host+"/settings?max_power=1800") || strings.Contains(out.String(), "Kitchen") || strings.Contains(out.String(), "Applied") {
+		t.Fatalf("unexpected dry run output:\n%s", out)
+	}
+
+	settings, _ := to.Settings(ctx)
+	if settings["max_power"] != 2500.0 {
+		t.Fatalf("dry run changed the device: %v", settings["max_power"])
+	}
+
+	dryRun = false
+	out.Reset()
+	err = copySettings(ctx, from, to, out)
+	if err != nil {
+		t.Fatalf("copy failed: %v", err)
+	}
+
+	settings, _ = to.Settings(ctx)
+	if settings["max_power"] != 1800.0 || settings["name"] != "Simulated Plug" {
+		t.Fatalf("unexpected settings after copy: %v %v", settings["max_power"], settings["name"])
+	}
+	if v, _ := lookupPath(settings, "relays.0.auto_off"); v != 60.0 {
+		t.Fatalf("expected auto_off to be 60 got %v", v)
+	}
+}
+
+func TestCopyChanges(t *testing.T) {
+	changes := []importChange{
+		{component: "cloud", key: "enable"},
+		{component: "mqtt", key: "client_id"},
+		{component: "mqtt", key: "server"},
+		{component: "wifi_ap", key: "ssid"},
+		{component: "switch:0", key: "name"},
+		{component: "switch:0", key: "auto_off"},
+	}
+
+	res := copyChanges(changes, []string{"wifi_ap,switch:0.name"})
+	if len(res) != 2 || res[0].key != "server" || res[1].key != "auto_off" {
+		t.Fatalf("unexpected changes: %+v", res)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/choria-io/fisk"
+	"golang.org/x/term"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	passwordStdin  bool
+	credentialFile string
+)
+
+// credentials is the content of the file set using --credential-file
+type credentials struct {
+	Username string `json:"username" yaml:"username"`
+	Password string `json:"password" yaml:"password"`
+}
+
+// configureCredentials reads credentials from the sources configured using flags, the credential file
+// takes precedence over --username and --password while --password-stdin takes precedence over all
+func configureCredentials(_ *fisk.ParseContext) error {
+	if credentialFile != "" {
+		creds, err := readCredentialFile(credentialFile)
+		if err != nil {
+			return err
+		}
+
+		user = creds.Username
+		pass = creds.Password
+	}
+
+	if passwordStdin {
+		var err error
+		pass, err = readPasswordStdin()
+		if err != nil {
+			return fmt.Errorf("could not read password from stdin: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// readCredentialFile reads a JSON or YAML file holding a username and password
+func readCredentialFile(file string) (*credentials, error) {
+	stat, err := os.Stat(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read credential file: %v", err)
+	}
+
+	if stat.Mode().Perm()&0077 != 0 {
+		slog.Warn("Credential file is accessible by other users", "file", file, "mode", stat.Mode().Perm())
+	}
+
+	cb, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read credential file: %v", err)
+	}
+
+	// YAML is a superset of JSON so this handles both formats
+	var creds credentials
+	err = yaml.Unmarshal(cb, &creds)
+	if err != nil {
+		return nil, fmt.Errorf("invalid credential file %s: %v", file, err)
+	}
+
+	if creds.Username == "" || creds.Password == "" {
+		return nil, fmt.Errorf("invalid credential file %s: username and password are required", file)
+	}
+
+	return &creds, nil
+}
+
+// readPasswordStdin reads the first line of stdin, when stdin is a terminal the input is not echoed
+func readPasswordStdin() (string, error) {
+	fd := int(os.Stdin.Fd())
+
+	if term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, "Password: ")
+		pw, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", err
+		}
+
+		return string(pw), nil
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,391 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/ripienaar/shellyctl"
+)
+
+var (
+	addressFile string
+
+	errNoAddresses = errors.New("required flag --address not provided")
+)
+
+// configureAddresses adds the devices listed in --address-file to those given using --address
+func configureAddresses(_ *fisk.ParseContext) error {
+	if addressFile == "" {
+		return nil
+	}
+
+	entries, err := readAddressFile(addressFile)
+	if err != nil {
+		return err
+	}
+
+	addresses = append(addresses, entries...)
+
+	return nil
+}
+
+// readAddressFile reads one device address per line, blank lines and lines starting with # are ignored
+func readAddressFile(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read address file: %v", err)
+	}
+	defer f.Close()
+
+	var entries []string
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+
+		if strings.ContainsAny(entry, " \t") {
+			return nil, fmt.Errorf("%s:%d: invalid address %q", file, line, entry)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, scanner.Err()
+}
+
+// deviceAction performs a command against a single device, all output should be written to w
+type deviceAction func(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error
+
+type deviceResult struct {
+	address string
+	out     bytes.Buffer
+	err     error
+}
+
+// forEachDevice runs action against every configured address using up to parallel workers,
+// output is buffered and printed in address order once all devices completed
+func forEachDevice(action deviceAction) error {
+	if len(addresses) == 0 {
+		return errNoAddresses
+	}
+
+	workers := parallel
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(addresses) {
+		workers = len(addresses)
+	}
+
+	fingerprints, err := loadFingerprints()
+	if err != nil {
+		return err
+	}
+
+	var breaker *breakerState
+	if circuitBreakerThreshold > 0 {
+		breaker, err = loadBreakerState()
+		if err != nil {
+			return err
+		}
+	}
+
+	results := make([]*deviceResult, len(addresses))
+	jobs := make(chan int, len(addresses))
+	for i, address := range addresses {
+		results[i] = &deviceResult{address: address}
+		jobs <- i
+	}
+	close(jobs)
+
+	wg := sync.WaitGroup{}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range jobs {
+				res := results[i]
+
+				if breaker != nil {
+					res.err = breaker.skip(res.address)
+					if res.err != nil {
+						slog.Warn("Skipping device that failed repeatedly, reset using circuit-breaker reset", "device", res.address)
+						continue
+					}
+				}
+
+				runDevice(ctx, res, action, fingerprints)
+
+				if breaker != nil {
+					breaker.record(res.address, res.err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if breaker != nil {
+		err = breaker.save()
+		if err != nil {
+			slog.Warn("Could not save circuit breaker state", "error", err)
+		}
+	}
+
+	var errs []error
+	defer func() { commandErr = errors.Join(errs...) }()
+
+	for i, res := range results {
+		if len(results) > 1 && i > 0 && res.out.Len() > 0 {
+			fmt.Fprintln(output)
+		}
+
+		output.Write(res.out.Bytes())
+
+		if res.err != nil {
+			if len(results) == 1 {
+				errs = append(errs, res.err)
+				return res.err
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", res.address, res.err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// runDevice connects to the device in res and runs action, the result is stored in res
+func runDevice(ctx context.Context, res *deviceResult, action deviceAction, fingerprints map[string]string) {
+	// in dry-run mode only the requests that would be made are shown
+	var out io.Writer = &res.out
+	var dryRunOut io.Writer
+	if dryRun {
+		out, dryRunOut = io.Discard, &res.out
+	}
+
+	if waitForDevice > 0 {
+		// the device type can only be detected once it is reachable
+		plug, _, err := newPlug(res.address, fingerprints, dryRunOut)
+		if err != nil {
+			res.err = err
+			return
+		}
+
+		err = waitForPlug(ctx, plug, waitForDevice)
+		if err != nil {
+			res.err = err
+			return
+		}
+	}
+
+	plug, err := newDevice(res.address, fingerprints, dryRunOut)
+	if err != nil {
+		res.err = err
+		return
+	}
+
+	res.err = action(ctx, res.address, plug, out)
+}
+
+var (
+	// plugs are reused for every device so repeated runs in watch mode reuse connections
+	plugs   = map[string]*cachedPlug{}
+	plugsMu sync.Mutex
+)
+
+// cachedPlug is the detected plug for an address, mu ensures the type of a device is detected once without
+// blocking requests to other devices
+type cachedPlug struct {
+	plug shellyctl.Plug
+	mu   sync.Mutex
+}
+
+// validateAddress rejects IP addresses that can not be a single device, hostnames are not resolved
+func validateAddress(address string) error {
+	host := address
+	if h, _, err := net.SplitHostPort(address); err == nil {
+		host = h
+	}
+
+	ip, err := netip.ParseAddr(strings.Trim(host, "[]"))
+	if err != nil {
+		return nil
+	}
+
+	switch {
+	case ip.IsUnspecified():
+		return fmt.Errorf("invalid address %s: the unspecified address does not identify a device", address)
+	case ip.IsLoopback():
+		return fmt.Errorf("invalid address %s: loopback addresses refer to this machine", address)
+	case ip.IsMulticast():
+		return fmt.Errorf("invalid address %s: multicast addresses reach a group of hosts", address)
+	case ip.Is4() && ip.As4()[0] == 255:
+		return fmt.Errorf("invalid address %s: broadcast addresses reach all hosts on the network", address)
+	}
+
+	return nil
+}
+
+// deviceHost formats address as the host of a URL, IPv6 addresses must be enclosed in brackets and addresses
+// that include a port are used as is
+func deviceHost(address string) string {
+	ip, err := netip.ParseAddr(address)
+	if err != nil || !ip.Is6() {
+		return address
+	}
+
+	// JoinHostPort adds the brackets, the empty port and its separator are removed
+	return strings.TrimSuffix(net.JoinHostPort(ip.String(), ""), ":")
+}
+
+// newDevice creates a Plug for the device at address using the global connection settings, detected dimmers, rollers
+// and sensors implement the matching interface. When dryRun is not nil requests that change the device are written
+// to it instead of being sent
+func newDevice(address string, fingerprints map[string]string, dryRun io.Writer) (shellyctl.Plug, error) {
+	if dryRun != nil {
+		return newDetectedDevice(address, fingerprints, dryRun)
+	}
+
+	plugsMu.Lock()
+	cached, ok := plugs[address]
+	if !ok {
+		cached = &cachedPlug{}
+		plugs[address] = cached
+	}
+	plugsMu.Unlock()
+
+	cached.mu.Lock()
+	defer cached.mu.Unlock()
+
+	if cached.plug != nil {
+		return cached.plug, nil
+	}
+
+	// failures are not cached so devices that are not reachable yet are detected on the next use
+	plug, err := newDetectedDevice(address, fingerprints, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	cached.plug = plug
+
+	return plug, nil
+}
+
+// newDetectedDevice creates a Plug and detects the type of the device, an unreachable device fails here instead of
+// timing out a second time in the command
+func newDetectedDevice(address string, fingerprints map[string]string, dryRun io.Writer) (shellyctl.Plug, error) {
+	plug, opts, err := newPlug(address, fingerprints, dryRun)
+	if err != nil {
+		return nil, err
+	}
+
+	detected, err := detectDevice(ctx, deviceUrl(address), plug, opts)
+	if err != nil {
+		slog.Debug("Could not detect the device type", "device", address, "error", err)
+		return nil, err
+	}
+
+	return detected, nil
+}
+
+// newPlug creates a relay Plug for the device at address without contacting it, the options used are returned
+// so the plug can be replaced once the device type is known
+func newPlug(address string, fingerprints map[string]string, dryRun io.Writer) (shellyctl.Plug, []shellyctl.Option, error) {
+	// simulated devices are served on the loopback address
+	if simulator == nil {
+		err := validateAddress(address)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	tlsc, err := tlsConfig(address, fingerprints)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	opts := []shellyctl.Option{
+		shellyctl.WithTimeout(timeout),
+		shellyctl.WithTLSConfig(tlsc),
+		shellyctl.WithSOCKS5Proxy(socksProxy),
+		shellyctl.WithUserAgent(userAgent),
+		shellyctl.WithDryRun(dryRun),
+		shellyctl.WithRateLimiter(requestLimiter),
+		shellyctl.WithCacheTTL(cacheTTL),
+	}
+	if user != "" && pass != "" {
+		opts = append(opts, shellyctl.WithCredentials(user, pass))
+	}
+
+	plug, err := shellyctl.NewPlug(deviceUrl(address), opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return plug, opts, nil
+}
+
+// detectDevice replaces plug with a Dimmer, Roller or Sensor based on the type of the device at address
+func detectDevice(ctx context.Context, address url.URL, plug shellyctl.Plug, opts []shellyctl.Option) (shellyctl.Plug, error) {
+	nfo, err := plug.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case nfo.IsDimmer():
+		return shellyctl.NewDimmer(address, opts...)
+	case nfo.IsRoller():
+		return shellyctl.NewRoller(address, nfo.Generation(), opts...)
+	case nfo.IsSensor():
+		return shellyctl.NewSensor(address, opts...)
+	}
+
+	return plug, nil
+}
+
+// waitForPlug retries fetching the device information with exponential backoff until it succeeds or timeout passes
+func waitForPlug(ctx context.Context, plug shellyctl.Plug, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	delay := 250 * time.Millisecond
+
+	for {
+		var nfo shellyctl.DeviceInfo
+		err := plug.Get(ctx, "shelly", &nfo)
+		if err == nil {
+			return nil
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("device did not become reachable within %v: %w", timeout, err)
+		}
+
+		slog.Info("Waiting for device", "delay", delay, "error", err)
+		err = sleep(ctx, min(delay, remaining))
+		if err != nil {
+			return err
+		}
+
+		delay *= 2
+		if delay > 5*time.Second {
+			delay = 5 * time.Second
+		}
+	}
+}
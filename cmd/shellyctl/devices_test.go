@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestValidateAddress(t *testing.T) {
+	cases := []struct {
+		address string
+		valid   bool
+	}{
+		{"192.168.1.10", true},
+		{"192.168.1.10:8080", true},
+		{"plug.local", true},
+		{"localhost", true},
+		{"127.0.0.1", false},
+		{"127.1.2.3:80", false},
+		{"0.0.0.0", false},
+		{"255.255.255.255", false},
+		{"255.1.2.3", false},
+		{"224.0.0.1", false},
+		{"239.255.255.250", false},
+		{"2001:db8::10", true},
+		{"[2001:db8::10]:8080", true},
+		{"::1", false},
+		{"[::1]", false},
+		{"[::1]:80", false},
+		{"ff02::1", false},
+	}
+
+	for _, c := range cases {
+		err := validateAddress(c.address)
+		if c.valid && err != nil {
+			t.Errorf("expected %s to be valid: %v", c.address, err)
+		}
+		if !c.valid && err == nil {
+			t.Errorf("expected %s to be rejected", c.address)
+		}
+	}
+}
+
+func TestDeviceUrl(t *testing.T) {
+	cases := []struct {
+		address  string
+		expected string
+	}{
+		{"192.168.1.10", "http://192.168.1.10"},
+		{"192.168.1.10:8080", "http://192.168.1.10:8080"},
+		{"plug.local", "http://plug.local"},
+		{"fe80::1", "http://[fe80::1]"},
+		{"2001:db8::10", "http://[2001:db8::10]"},
+		{"[2001:db8::10]", "http://[2001:db8::10]"},
+		{"[2001:db8::10]:8080", "http://[2001:db8::10]:8080"},
+		{"fe80::1%eth0", "http://[fe80::1%25eth0]"},
+	}
+
+	for _, c := range cases {
+		u := deviceUrl(c.address)
+		if u.String() != c.expected {
+			t.Errorf("expected %s to be %s got %s", c.address, c.expected, u.String())
+		}
+	}
+}
+
+func TestNewDeviceDetection(t *testing.T) {
+	var detections atomic.Int32
+	var down atomic.Bool
+	down.Store(true)
+
+	sim := newSimulatedPlug()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/shelly" {
+			detections.Add(1)
+			if down.Load() {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+		}
+		sim.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	prevCtx, prevSim := ctx, simulator
+	t.Cleanup(func() {
+		ctx, simulator = prevCtx, prevSim
+		plugsMu.Lock()
+		delete(plugs, "detect.example.net")
+		plugsMu.Unlock()
+	})
+	ctx, simulator = context.Background(), srv
+
+	_, err := newDevice("detect.example.net", nil, nil)
+	if err == nil {
+		t.Fatalf("expected an unreachable device to fail")
+	}
+
+	down.Store(false)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := newDevice("detect.example.net", nil, nil)
+			if err != nil {
+				t.Errorf("could not create device: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := detections.Load(); n != 2 {
+		t.Fatalf("expected the failure and one successful detection, got %d detections", n)
+	}
+}
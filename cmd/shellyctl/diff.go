@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/choria-io/fisk"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+var (
+	diffFrom string
+	diffTo   string
+)
+
+func diffAction(_ *fisk.ParseContext) error {
+	fingerprints, err := loadFingerprints()
+	if err != nil {
+		return err
+	}
+
+	from, err := diffConfig(ctx, diffFrom, fingerprints)
+	if err != nil {
+		return fmt.Errorf("%s: %w", diffFrom, err)
+	}
+
+	to, err := diffConfig(ctx, diffTo, fingerprints)
+	if err != nil {
+		return fmt.Errorf("%s: %w", diffTo, err)
+	}
+
+	lines := diffLines(from, to)
+	hunks := diffHunks(lines, 3)
+
+	if len(hunks) == 0 {
+		fmt.Fprintf(output, "Configuration of %s and %s is identical\n", diffFrom, diffTo)
+		return nil
+	}
+
+	fmt.Fprintln(output, colorBad.Sprintf("--- %s", diffFrom))
+	fmt.Fprintln(output, colorGood.Sprintf("+++ %s", diffTo))
+
+	for _, line := range hunks {
+		switch line[0] {
+		case '-':
+			fmt.Fprintln(output, colorBad.Sprint(line))
+		case '+':
+			fmt.Fprintln(output, colorGood.Sprint(line))
+		default:
+			fmt.Fprintln(output, line)
+		}
+	}
+
+	// like diff the command fails when the configurations differ so scripts can detect changes
+	return fmt.Errorf("configuration of %s and %s differs", diffFrom, diffTo)
+}
+
+// diffLine is a line of a document prefixed by op, - when it is only in the first document, + when it is only in
+// the second and a space when it is in both
+type diffLine struct {
+	op   byte
+	text string
+}
+
+// diffLines compares a and b line by line
+func diffLines(a string, b string) []diffLine {
+	dmp := diffmatchpatch.New()
+	ca, cb, lines := dmp.DiffLinesToChars(a, b)
+	diffs := dmp.DiffCharsToLines(dmp.DiffMain(ca, cb, false), lines)
+
+	var res []diffLine
+	for _, d := range diffs {
+		op := byte(' ')
+		switch d.Type {
+		case diffmatchpatch.DiffDelete:
+			op = '-'
+		case diffmatchpatch.DiffInsert:
+			op = '+'
+		}
+
+		for _, line := range strings.SplitAfter(d.Text, "\n") {
+			if line != "" {
+				res = append(res, diffLine{op, strings.TrimSuffix(line, "\n")})
+			}
+		}
+	}
+
+	return res
+}
+
+// diffHunks formats the changed lines as unified diff hunks, each change is shown with up to context unchanged
+// lines around it and changes close to each other share a hunk
+func diffHunks(lines []diffLine, context int) []string {
+	// the line numbers in each document of every line, used in the hunk headers
+	aPos, bPos := make([]int, len(lines)), make([]int, len(lines))
+	var changes []int
+	a, b := 1, 1
+	for i, line := range lines {
+		aPos[i], bPos[i] = a, b
+		if line.op != '+' {
+			a++
+		}
+		if line.op != '-' {
+			b++
+		}
+		if line.op != ' ' {
+			changes = append(changes, i)
+		}
+	}
+
+	var res []string
+	for c := 0; c < len(changes); c++ {
+		start := max(changes[c]-context, 0)
+		for c+1 < len(changes) && changes[c+1]-changes[c] <= 2*context+1 {
+			c++
+		}
+		end := min(changes[c]+context, len(lines)-1)
+
+		aCount, bCount := 0, 0
+		for _, line := range lines[start : end+1] {
+			if line.op != '+' {
+				aCount++
+			}
+			if line.op != '-' {
+				bCount++
+			}
+		}
+
+		res = append(res, fmt.Sprintf("@@ -%s +%s @@", hunkRange(aPos[start], aCount), hunkRange(bPos[start], bCount)))
+		for _, line := range lines[start : end+1] {
+			res = append(res, string(line.op)+line.text)
+		}
+	}
+
+	return res
+}
+
+// hunkRange formats the start and length of a hunk, empty ranges start at the line before the hunk
+func hunkRange(start int, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start-1)
+	case 1:
+		return fmt.Sprint(start)
+	default:
+		return fmt.Sprintf("%d,%d", start, count)
+	}
+}
+
+// diffConfig retrieves the configuration of the device at address formatted for comparison
+func diffConfig(ctx context.Context, address string, fingerprints map[string]string) (string, error) {
+	plug, err := newDevice(address, fingerprints, nil)
+	if err != nil {
+		return "", err
+	}
+
+	cfg, err := deviceConfig(ctx, plug)
+	if err != nil {
+		return "", err
+	}
+
+	j, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	return string(j) + "\n", nil
+}
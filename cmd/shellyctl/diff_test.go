@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDiffHunks(t *testing.T) {
+	var a, b []string
+	for i := 1; i <= 20; i++ {
+		a = append(a, fmt.Sprintf("line %d", i))
+	}
+	b = append(b, a...)
+	b[1] = "changed 2"
+	b = append(b[:10], b[11:]...)
+	b = append(b, "line 21")
+
+	hunks := diffHunks(diffLines(strings.Join(a, "\n")+"\n", strings.Join(b, "\n")+"\n"), 3)
+
+	expected := []string{
+		"@@ -1,5 +1,5 @@",
+		" line 1",
+		"-line 2",
+		"+changed 2",
+		" line 3",
+		" line 4",
+		" line 5",
+		"@@ -8,7 +8,6 @@",
+		" line 8",
+		" line 9",
+		" line 10",
+		"-line 11",
+		" line 12",
+		" line 13",
+		" line 14",
+		"@@ -18,3 +17,4 @@",
+		" line 18",
+		" line 19",
+		" line 20",
+		"+line 21",
+	}
+
+	if strings.Join(hunks, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("unexpected hunks:\n%s", strings.Join(hunks, "\n"))
+	}
+
+	if hunks := diffHunks(diffLines("a\nb\n", "a\nb\n"), 3); len(hunks) != 0 {
+		t.Fatalf("expected no hunks for identical documents: %v", hunks)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/choria-io/fisk"
+	"github.com/ripienaar/shellyctl"
+)
+
+func ecoModeGetAction(_ *fisk.ParseContext) error {
+	return forEachDevice(ecoModeGetDevice)
+}
+
+func ecoModeGetDevice(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+	_, err := requireGen2(ctx, plug)
+	if err != nil {
+		return err
+	}
+
+	var cfg shellyctl.Gen2SysConfig
+	err = plug.RPC(ctx, "Sys.GetConfig", nil, &cfg)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Eco mode on %s: %s\n", address, onOffString(cfg.Device.EcoMode))
+
+	return nil
+}
+
+func ecoModeEnableAction(_ *fisk.ParseContext) error {
+	return forEachDevice(func(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+		return ecoModeSetDevice(ctx, address, plug, true, w)
+	})
+}
+
+func ecoModeDisableAction(_ *fisk.ParseContext) error {
+	return forEachDevice(func(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+		return ecoModeSetDevice(ctx, address, plug, false, w)
+	})
+}
+
+func ecoModeSetDevice(ctx context.Context, address string, plug shellyctl.Plug, enable bool, w io.Writer) error {
+	_, err := requireGen2(ctx, plug)
+	if err != nil {
+		return err
+	}
+
+	params := map[string]any{"config": map[string]any{"device": map[string]any{"eco_mode": enable}}}
+	err = plug.RPC(ctx, "Sys.SetConfig", params, &map[string]any{})
+	if err != nil {
+		return err
+	}
+
+	if enable {
+		fmt.Fprintf(w, "Eco mode enabled on %s\n", address)
+	} else {
+		fmt.Fprintf(w, "Eco mode disabled on %s\n", address)
+	}
+	fmt.Fprintln(w, "Eco mode changes take effect after the device is rebooted")
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+)
+
+var (
+	// legacyEnvars maps the environment variables used before flags could be set using SHELLYCTL_<FLAG> to their
+	// new names, they are deprecated and will be removed in the next release
+	legacyEnvars = map[string]string{
+		"ADDRESS":  "SHELLYCTL_ADDRESS",
+		"USERNAME": "SHELLYCTL_USERNAME",
+		"PASSWORD": "SHELLYCTL_PASSWORD",
+	}
+
+	// usedLegacyEnvars are the legacy variables that were applied, a warning is logged once logging is configured
+	usedLegacyEnvars []string
+)
+
+// applyLegacyEnvars sets the SHELLYCTL_ variable from its legacy name when only the legacy one is set
+func applyLegacyEnvars() {
+	for _, legacy := range sortedKeys(legacyEnvars) {
+		value := os.Getenv(legacy)
+		if value == "" {
+			continue
+		}
+
+		if _, ok := os.LookupEnv(legacyEnvars[legacy]); ok {
+			continue
+		}
+
+		os.Setenv(legacyEnvars[legacy], value)
+		usedLegacyEnvars = append(usedLegacyEnvars, legacy)
+	}
+}
+
+func warnLegacyEnvars() {
+	for _, legacy := range usedLegacyEnvars {
+		slog.Warn("Environment variable is deprecated and will be removed in the next release", "variable", legacy, "replacement", legacyEnvars[legacy])
+	}
+}
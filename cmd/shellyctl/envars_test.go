@@ -0,0 +1,29 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestApplyLegacyEnvars(t *testing.T) {
+	t.Cleanup(func() { usedLegacyEnvars = nil })
+
+	t.Setenv("ADDRESS", "192.168.1.10")
+	t.Setenv("SHELLYCTL_ADDRESS", "")
+	os.Unsetenv("SHELLYCTL_ADDRESS")
+	t.Setenv("USERNAME", "admin")
+	t.Setenv("SHELLYCTL_USERNAME", "other")
+	t.Setenv("PASSWORD", "")
+
+	applyLegacyEnvars()
+
+	if v := os.Getenv("SHELLYCTL_ADDRESS"); v != "192.168.1.10" {
+		t.Fatalf("expected ADDRESS to be used got %q", v)
+	}
+	if v := os.Getenv("SHELLYCTL_USERNAME"); v != "other" {
+		t.Fatalf("expected SHELLYCTL_USERNAME to take precedence got %q", v)
+	}
+	if len(usedLegacyEnvars) != 1 || usedLegacyEnvars[0] != "ADDRESS" {
+		t.Fatalf("unexpected legacy variables %v", usedLegacyEnvars)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"sync"
+
+	"github.com/ripienaar/shellyctl"
+)
+
+const (
+	exitRelayOff        = 2
+	exitUnreachable     = 3
+	exitAuthFailed      = 4
+	exitUpdateAvailable = 5
+)
+
+var (
+	machineExitCode bool
+
+	// commandErr is the error the command failed with, used to determine the exit code
+	commandErr error
+)
+
+const exitCodesHelp = `When --machine-exit-code is set these exit codes are used:
+
+  2  The relay is off after turning it on
+  3  The device is unreachable
+  4  Authentication failed
+  5  A firmware update is available, set by info`
+
+// terminate exits with a code describing the failure when --machine-exit-code is set, commands should
+// use it rather than os.Exit so exit hooks are run
+func terminate(status int) {
+	switch {
+	case status != 1:
+	case machineExitCode:
+		status = exitCodeFor(commandErr)
+	case errors.Is(commandErr, errAllPingsFailed):
+		status = 2
+	}
+
+	if status != 0 {
+		commandFailed(status)
+	}
+
+	runExitHooks()
+
+	os.Exit(status)
+}
+
+// completed reports success and runs the exit hooks once the command returned without error, including
+// watch mode commands stopped by an interrupt
+func completed() {
+	commandSucceeded()
+	runExitHooks()
+}
+
+func exitCodeFor(err error) int {
+	switch {
+	case errors.Is(err, shellyctl.ErrRelayNotOn):
+		return exitRelayOff
+	case errors.Is(err, shellyctl.ErrDeviceUnreachable):
+		return exitUnreachable
+	case errors.Is(err, shellyctl.ErrAuthFailed):
+		return exitAuthFailed
+	default:
+		return 1
+	}
+}
+
+var (
+	exitHooks   []func()
+	exitHooksMu sync.Mutex
+)
+
+// atExit registers fn to be called when the command completes or exits using terminate, interrupting the
+// process cancels ctx so commands return and the hooks run the same way
+func atExit(fn func()) {
+	exitHooksMu.Lock()
+	exitHooks = append(exitHooks, fn)
+	exitHooksMu.Unlock()
+}
+
+// runExitHooks calls the functions registered using atExit in reverse order, each is called only once
+func runExitHooks() {
+	exitHooksMu.Lock()
+	hooks := exitHooks
+	exitHooks = nil
+	exitHooksMu.Unlock()
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i]()
+	}
+}
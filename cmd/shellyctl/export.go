@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/choria-io/fisk"
+	"github.com/ripienaar/shellyctl"
+)
+
+var exportFile string
+
+// deviceExport is the document produced by export, it holds the raw responses from the device
+type deviceExport struct {
+	Info   map[string]any `json:"info"`
+	Status map[string]any `json:"status"`
+	Config map[string]any `json:"config"`
+}
+
+func exportAction(_ *fisk.ParseContext) error {
+	if exportFile != "" && len(addresses) > 1 {
+		return fmt.Errorf("only a single device can be exported to a file")
+	}
+
+	return forEachDevice(exportDevice)
+}
+
+// exportPlug retrieves the information, status and configuration of a device
+func exportPlug(ctx context.Context, plug shellyctl.Plug) (*deviceExport, error) {
+	nfo, err := plug.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var res deviceExport
+
+	if nfo.Generation() == 1 {
+		err = plug.Get(ctx, "shelly", &res.Info)
+		if err == nil {
+			err = plug.Get(ctx, "status", &res.Status)
+		}
+		if err == nil {
+			err = plug.Get(ctx, "settings", &res.Config)
+		}
+	} else {
+		err = plug.RPC(ctx, "Shelly.GetDeviceInfo", nil, &res.Info)
+		if err == nil {
+			err = plug.RPC(ctx, "Shelly.GetStatus", nil, &res.Status)
+		}
+		if err == nil {
+			err = plug.RPC(ctx, "Shelly.GetConfig", nil, &res.Config)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+func exportDevice(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+	export, err := exportPlug(ctx, plug)
+	if err != nil {
+		return err
+	}
+
+	j, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if exportFile == "" {
+		fmt.Fprintln(w, string(j))
+		return nil
+	}
+
+	err = os.WriteFile(exportFile, append(j, '\n'), 0600)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Exported %s to %s\n", address, exportFile)
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/ripienaar/shellyctl"
+)
+
+var (
+	exporterListen   string
+	exporterInterval time.Duration
+
+	promUp         *prometheus.GaugeVec
+	promPower      *prometheus.GaugeVec
+	promEnergy     *prometheus.GaugeVec
+	promRelayOn    *prometheus.GaugeVec
+	promPollErrors *prometheus.CounterVec
+)
+
+// newExporterMetrics creates the metrics served by the exporter, every metric has the address label and the
+// labels set using --label and --device-label
+func newExporterMetrics() {
+	names := map[string]bool{}
+	for k := range labels {
+		names[k] = true
+	}
+	if deviceLabels {
+		names["device"], names["ip"] = true, true
+	}
+	delete(names, "address")
+
+	labelNames := append([]string{"address"}, sortedKeys(names)...)
+
+	promUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "shelly_up",
+		Help: "Whether the last poll of the device succeeded",
+	}, labelNames)
+
+	promPower = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "shelly_power_watts",
+		Help: "Current power usage in Watt",
+	}, labelNames)
+
+	promEnergy = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "shelly_energy_kwh",
+		Help: "Energy used since the device was reset in kWh",
+	}, labelNames)
+
+	promRelayOn = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "shelly_relay_on",
+		Help: "Whether the relay is on",
+	}, labelNames)
+
+	promPollErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "shelly_poll_errors_total",
+		Help: "Number of times polling the device failed",
+	}, labelNames)
+}
+
+// exporterLabels are the label values for the metrics of the device at address
+func exporterLabels(ctx context.Context, address string, plug shellyctl.Plug) prometheus.Labels {
+	res := prometheus.Labels(metricLabels(ctx, address, plug))
+	res["address"] = address
+
+	return res
+}
+
+// exporterAction polls the devices every interval in the background and serves the latest readings
+// on /metrics, scrapes never wait for the devices
+func exporterAction(_ *fisk.ParseContext) error {
+	if len(addresses) == 0 {
+		return errNoAddresses
+	}
+
+	newExporterMetrics()
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(promUp, promPower, promEnergy, promRelayOn, promPollErrors)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+
+	srv := &http.Server{Addr: exporterListen, Handler: mux}
+
+	go func() {
+		for {
+			err := forEachDevice(exporterDevice)
+			if err != nil && ctx.Err() == nil {
+				slog.Warn("Polling devices failed", "error", err)
+			}
+
+			if sleep(ctx, exporterInterval) != nil {
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				srv.Shutdown(shutdownCtx)
+				cancel()
+				return
+			}
+		}
+	}()
+
+	slog.Info("Serving Prometheus metrics", "listen", exporterListen)
+
+	err := srv.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
+
+func exporterDevice(ctx context.Context, address string, plug shellyctl.Plug, _ io.Writer) error {
+	labels := exporterLabels(ctx, address, plug)
+
+	err := exporterPoll(ctx, plug, labels)
+	if err != nil {
+		promUp.With(labels).Set(0)
+		promPollErrors.With(labels).Inc()
+		return err
+	}
+
+	promUp.With(labels).Set(1)
+
+	return nil
+}
+
+func exporterPoll(ctx context.Context, plug shellyctl.Plug, labels prometheus.Labels) error {
+	status, err := plug.Status(ctx)
+	if err != nil {
+		return err
+	}
+	if len(status.Meters) != 1 || len(status.Relays) != 1 {
+		return fmt.Errorf("no meter information received")
+	}
+
+	isOn := float64(0)
+	if status.Relays[0].IsOn {
+		isOn = 1
+	}
+
+	promPower.With(labels).Set(status.Meters[0].Power)
+	promEnergy.With(labels).Set(status.Meters[0].TotalKWh())
+	promRelayOn.With(labels).Set(isOn)
+
+	return nil
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/choria-io/fisk"
+	"github.com/ripienaar/shellyctl"
+)
+
+var (
+	pinnedFingerprint string
+
+	// trustOnFirstUse records the fingerprint of untrusted device certificates on the first connection
+	trustOnFirstUse bool
+
+	fingerprintsMu sync.Mutex
+)
+
+// fingerprintsFile is the file that stores fingerprints recorded using the pin-fingerprint command
+func fingerprintsFile() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "shellyctl", "fingerprints.json"), nil
+}
+
+// loadFingerprints reads the pinned fingerprints keyed by device address
+func loadFingerprints() (map[string]string, error) {
+	file, err := fingerprintsFile()
+	if err != nil {
+		return nil, err
+	}
+
+	fingerprints := map[string]string{}
+
+	fb, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return fingerprints, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(fb, &fingerprints)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fingerprints file %s: %v", file, err)
+	}
+
+	return fingerprints, nil
+}
+
+func saveFingerprint(address string, fingerprint string) error {
+	fingerprintsMu.Lock()
+	defer fingerprintsMu.Unlock()
+
+	fingerprints, err := loadFingerprints()
+	if err != nil {
+		return err
+	}
+
+	fingerprints[address] = fingerprint
+
+	file, err := fingerprintsFile()
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(file), 0700)
+	if err != nil {
+		return err
+	}
+
+	j, err := json.MarshalIndent(fingerprints, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(file, j, 0600)
+}
+
+// certFingerprint calculates the fingerprint of a certificate in the SHA256:<hex> format
+func certFingerprint(raw []byte) string {
+	sum := sha256.Sum256(raw)
+	return "SHA256:" + hex.EncodeToString(sum[:])
+}
+
+// normalizeFingerprint accepts fingerprints with or without the SHA256: prefix and with or without colons
+func normalizeFingerprint(fingerprint string) string {
+	fp := strings.TrimPrefix(strings.TrimSpace(fingerprint), "SHA256:")
+	fp = strings.ToLower(strings.ReplaceAll(fp, ":", ""))
+
+	return "SHA256:" + fp
+}
+
+// verifyFingerprint verifies that the certificate presented by the device matches fingerprint without validating the chain
+func verifyFingerprint(fingerprint string) func([][]byte, [][]*x509.Certificate) error {
+	expected := normalizeFingerprint(fingerprint)
+
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return fmt.Errorf("no certificates presented by the device")
+		}
+
+		actual := certFingerprint(rawCerts[0])
+		if actual != expected {
+			return fmt.Errorf("certificate fingerprint %s does not match pinned fingerprint %s", actual, expected)
+		}
+
+		return nil
+	}
+}
+
+func pinFingerprintAction(_ *fisk.ParseContext) error {
+	return forEachDevice(pinFingerprintDevice)
+}
+
+func pinFingerprintDevice(ctx context.Context, address string, _ shellyctl.Plug, w io.Writer) error {
+	cert, err := deviceCertificate(ctx, address)
+	if err != nil {
+		return err
+	}
+
+	fingerprint := certFingerprint(cert.Raw)
+
+	err = saveFingerprint(address, fingerprint)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Pinned certificate %s for %s\n", fingerprint, address)
+	fmt.Fprintf(w, "             Subject: %s\n", cert.Subject)
+	fmt.Fprintf(w, "             Expires: %s\n", cert.NotAfter)
+
+	return nil
+}
+
+// deviceCertificate connects to the HTTPS port of the device, 443 unless address includes a port, using the
+// configured proxy and returns the certificate it presents without verifying it
+func deviceCertificate(ctx context.Context, address string) (*x509.Certificate, error) {
+	host := deviceHost(address)
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(strings.Trim(host, "[]"), "443")
+	}
+
+	dialer, err := shellyctl.NewDialer(shellyctl.WithTimeout(timeout), shellyctl.WithSOCKS5Proxy(socksProxy))
+	if err != nil {
+		return nil, err
+	}
+
+	nc, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", shellyctl.ErrDeviceUnreachable, err)
+	}
+	defer nc.Close()
+
+	tc := tls.Client(nc, &tls.Config{InsecureSkipVerify: true})
+	err = tc.HandshakeContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", shellyctl.ErrDeviceUnreachable, err)
+	}
+
+	certs := tc.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificates presented by the device")
+	}
+
+	return certs[0], nil
+}
+
+// verifyOrPin accepts certificates trusted by the roots in cfg, other certificates are trusted on first use by
+// recording their fingerprint for address, later connections only accept the recorded certificate
+func verifyOrPin(address string, cfg *tls.Config) func([][]byte, [][]*x509.Certificate) error {
+	var (
+		pinned string
+		mu     sync.Mutex
+	)
+
+	verifyChain := verifyChainOnly(cfg)
+	u := deviceUrl(address)
+	hostname := u.Hostname()
+
+	return func(rawCerts [][]byte, chains [][]*x509.Certificate) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if pinned != "" {
+			return verifyFingerprint(pinned)(rawCerts, chains)
+		}
+
+		if len(rawCerts) == 0 {
+			return fmt.Errorf("no certificates presented by the device")
+		}
+
+		if verifyChain(rawCerts, chains) == nil {
+			cert, err := x509.ParseCertificate(rawCerts[0])
+			if err == nil && cert.VerifyHostname(hostname) == nil {
+				return nil
+			}
+		}
+
+		fingerprint := certFingerprint(rawCerts[0])
+		err := saveFingerprint(address, fingerprint)
+		if err != nil {
+			return fmt.Errorf("could not pin the device certificate: %v", err)
+		}
+		pinned = fingerprint
+
+		slog.Warn("Trusting the device certificate on first use", "device", address, "fingerprint", fingerprint)
+
+		return nil
+	}
+}
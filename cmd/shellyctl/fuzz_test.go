@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ripienaar/shellyctl"
+)
+
+// fuzzPlug answers Info and Status with fixed responses and every RPC call by unmarshaling body, other
+// methods panic as they are not implemented
+type fuzzPlug struct {
+	shellyctl.Plug
+
+	info   *shellyctl.DeviceInfo
+	status *shellyctl.DeviceStatus
+	body   []byte
+}
+
+func (p *fuzzPlug) Info(_ context.Context) (*shellyctl.DeviceInfo, error) { return p.info, nil }
+
+func (p *fuzzPlug) Status(_ context.Context) (*shellyctl.DeviceStatus, error) { return p.status, nil }
+
+func (p *fuzzPlug) RPC(_ context.Context, _ string, _ any, response any) error {
+	return json.Unmarshal(p.body, response)
+}
+
+// addSeeds adds the device responses in testdata to the seed corpus of f
+func addSeeds(f *testing.F, files ...string) {
+	for _, file := range files {
+		body, err := os.ReadFile(filepath.Join("testdata", file))
+		if err != nil {
+			f.Fatalf("could not read seed: %v", err)
+		}
+
+		f.Add(body)
+	}
+
+	f.Add([]byte(`{}`))
+	f.Add([]byte(`null`))
+}
+
+// loadResponse reads a device response from testdata into v
+func loadResponse(f *testing.F, file string, v any) {
+	body, err := os.ReadFile(filepath.Join("testdata", file))
+	if err != nil {
+		f.Fatalf("could not read %s: %v", file, err)
+	}
+
+	err = json.Unmarshal(body, v)
+	if err != nil {
+		f.Fatalf("invalid %s: %v", file, err)
+	}
+}
+
+func FuzzDeviceStatusV1(f *testing.F) {
+	addSeeds(f, "gen1/status.json", "malformed/status.json")
+
+	var info shellyctl.DeviceInfo
+	loadResponse(f, "gen1/shelly.json", &info)
+
+	address := "192.168.1.10"
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		var status shellyctl.DeviceStatus
+		if json.Unmarshal(body, &status) != nil {
+			return
+		}
+
+		plug := &fuzzPlug{info: &info, status: &status}
+
+		energyDevice(context.Background(), address, plug, io.Discard)
+		infoDevice(context.Background(), address, plug, &atomic.Bool{}, io.Discard)
+	})
+}
+
+func FuzzDeviceStatusV2(f *testing.F) {
+	addSeeds(f, "gen2/rpc_WiFi.GetStatus.json", "gen2/rpc_WiFi.GetConfig.json", "gen2/rpc_Input.GetStatus.json")
+
+	var info shellyctl.DeviceInfo
+	loadResponse(f, "gen2/shelly.json", &info)
+
+	address := "192.168.1.11"
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		plug := &fuzzPlug{info: &info, body: body}
+
+		networkShowDevice(context.Background(), address, plug, io.Discard)
+		inputStatusDevice(context.Background(), address, plug, io.Discard)
+	})
+}
+
+func FuzzDeviceInfoV2(f *testing.F) {
+	addSeeds(f, "gen2/shelly.json", "gen1/shelly.json")
+
+	var status shellyctl.DeviceStatus
+	loadResponse(f, "gen1/status.json", &status)
+
+	address := "192.168.1.11"
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		var info shellyctl.DeviceInfo
+		if json.Unmarshal(body, &info) != nil {
+			return
+		}
+
+		plug := &fuzzPlug{info: &info, status: &status}
+
+		requireGen2(context.Background(), plug)
+		infoDevice(context.Background(), address, plug, &atomic.Bool{}, io.Discard)
+	})
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ripienaar/shellyctl"
+)
+
+// gen2Component describes a Gen 2 configuration component
+type gen2Component struct {
+	Namespace string // RPC namespace like Switch
+	Numbered  bool   // Whether the component has multiple instances identified by id
+}
+
+// gen2Components maps Gen 2 configuration component names to their RPC details
+var gen2Components = map[string]gen2Component{
+	"ble":      {"BLE", false},
+	"cloud":    {"Cloud", false},
+	"cover":    {"Cover", true},
+	"input":    {"Input", true},
+	"light":    {"Light", true},
+	"mqtt":     {"MQTT", false},
+	"plugs_ui": {"PLUGS_UI", false},
+	"switch":   {"Switch", true},
+	"sys":      {"Sys", false},
+	"ui":       {"UI", false},
+	"wifi":     {"WiFi", false},
+	"ws":       {"Ws", false},
+}
+
+// gen2ComponentNames is the sorted list of known Gen 2 component names
+func gen2ComponentNames() []string {
+	var names []string
+	for name := range gen2Components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// gen2ComponentMethod determines the RPC method for a component key as found in Shelly.GetConfig like switch:0
+// or sys, the id is -1 for components that are not numbered
+func gen2ComponentMethod(key string, method string) (string, int, error) {
+	name, idx, numbered := strings.Cut(key, ":")
+
+	component, ok := gen2Components[name]
+	if !ok {
+		return "", -1, fmt.Errorf("unsupported component %q, supported components are: %s", name, strings.Join(gen2ComponentNames(), ", "))
+	}
+
+	if !numbered {
+		return fmt.Sprintf("%s.%s", component.Namespace, method), -1, nil
+	}
+
+	id, err := strconv.Atoi(idx)
+	if err != nil {
+		return "", -1, fmt.Errorf("invalid component id in %q: %v", key, err)
+	}
+
+	return fmt.Sprintf("%s.%s", component.Namespace, method), id, nil
+}
+
+// gen2ComponentKey creates the key used in Shelly.GetConfig for a component, id is ignored for components without instances
+func gen2ComponentKey(name string, id int) string {
+	component, ok := gen2Components[name]
+	if !ok || !component.Numbered {
+		return name
+	}
+
+	return fmt.Sprintf("%s:%d", name, id)
+}
+
+// requireGen1 ensures that plug is a Gen 1 device
+func requireGen1(ctx context.Context, plug shellyctl.Plug) (*shellyctl.DeviceInfo, error) {
+	nfo, err := plug.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if nfo.Generation() != 1 {
+		return nil, fmt.Errorf("this command requires a Gen 1 device, %s is a Gen %d device", nfo.Model, nfo.Generation())
+	}
+
+	return nfo, nil
+}
+
+// requireGen2 ensures that plug is a Gen 2 or newer device
+func requireGen2(ctx context.Context, plug shellyctl.Plug) (*shellyctl.DeviceInfo, error) {
+	nfo, err := plug.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if nfo.Generation() < 2 {
+		return nil, fmt.Errorf("this command requires a Gen 2 device, %s is a Gen 1 device", nfo.Type)
+	}
+
+	return nfo, nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+var grafanaListen string
+
+// grafanaMetrics are the metrics offered to Grafana for every device
+var grafanaMetrics = []string{"power_watt", "power_total_kwh", "relay_on"}
+
+type grafanaQuery struct {
+	Targets []struct {
+		Target string `json:"target"`
+	} `json:"targets"`
+}
+
+type grafanaSeries struct {
+	Target     string       `json:"target"`
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+// serveGrafana serves a Grafana SimpleJSON datasource, devices are queried whenever Grafana requests data
+func serveGrafana(listen string) error {
+	if len(addresses) == 0 {
+		return errNoAddresses
+	}
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "OK")
+	})
+
+	mux.HandleFunc("/search", func(w http.ResponseWriter, r *http.Request) {
+		var targets []string
+		for _, address := range addresses {
+			for _, metric := range grafanaMetrics {
+				targets = append(targets, grafanaTarget(address, metric))
+			}
+		}
+
+		grafanaRespond(w, targets)
+	})
+
+	mux.HandleFunc("/query", func(w http.ResponseWriter, r *http.Request) {
+		var query grafanaQuery
+		err := json.NewDecoder(r.Body).Decode(&query)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		series, err := grafanaSeriesFor(r.Context(), query)
+		if err != nil {
+			slog.Warn("Querying devices failed", "error", err)
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+
+		grafanaRespond(w, series)
+	})
+
+	srv := &http.Server{Addr: listen, Handler: mux}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		srv.Shutdown(shutdownCtx)
+		cancel()
+	}()
+
+	slog.Info("Serving Grafana SimpleJSON datasource", "listen", listen)
+
+	err := srv.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
+
+// grafanaTarget is the name of the metric for the device at address shown in Grafana
+func grafanaTarget(address string, metric string) string {
+	return fmt.Sprintf("%s %s", address, metric)
+}
+
+// grafanaSeriesFor reads the current values of all requested targets, each device is contacted once
+func grafanaSeriesFor(ctx context.Context, query grafanaQuery) ([]grafanaSeries, error) {
+	fingerprints, err := loadFingerprints()
+	if err != nil {
+		return nil, err
+	}
+
+	readings := map[string]map[string]float64{}
+	series := []grafanaSeries{}
+	now := float64(time.Now().UnixMilli())
+
+	for _, t := range query.Targets {
+		address, metric, ok := strings.Cut(t.Target, " ")
+		if !ok {
+			return nil, fmt.Errorf("invalid target %q", t.Target)
+		}
+
+		reading, ok := readings[address]
+		if !ok {
+			reading, err = grafanaReading(ctx, address, fingerprints)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", address, err)
+			}
+			readings[address] = reading
+		}
+
+		value, ok := reading[metric]
+		if !ok {
+			return nil, fmt.Errorf("unknown metric %q", metric)
+		}
+
+		series = append(series, grafanaSeries{Target: t.Target, Datapoints: [][2]float64{{value, now}}})
+	}
+
+	return series, nil
+}
+
+func grafanaReading(ctx context.Context, address string, fingerprints map[string]string) (map[string]float64, error) {
+	// only configured devices are queried so Grafana cannot be used to reach arbitrary hosts
+	if !slices.Contains(addresses, address) {
+		return nil, fmt.Errorf("unknown device")
+	}
+
+	plug, err := newDevice(address, fingerprints, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := plug.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(status.Meters) != 1 || len(status.Relays) != 1 {
+		return nil, fmt.Errorf("no meter information received")
+	}
+
+	isOn := float64(0)
+	if status.Relays[0].IsOn {
+		isOn = 1
+	}
+
+	return map[string]float64{
+		"power_watt":      status.Meters[0].Power,
+		"power_total_kwh": status.Meters[0].TotalKWh(),
+		"relay_on":        isOn,
+	}, nil
+}
+
+func grafanaRespond(w http.ResponseWriter, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(data)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+var (
+	graphiteAddress string
+	graphitePrefix  string
+)
+
+// graphitePath builds the metric path for metric of the device at address, label values are added in key order
+func graphitePath(address string, labels map[string]string, metric string) string {
+	parts := []string{graphitePrefix}
+	for _, k := range sortedKeys(labels) {
+		parts = append(parts, graphiteComponent(labels[k]))
+	}
+	parts = append(parts, graphiteComponent(address), metric)
+
+	return strings.Join(parts, ".")
+}
+
+// graphiteComponent makes v safe to use as a single component of a metric path
+func graphiteComponent(v string) string {
+	return strings.NewReplacer(".", "_", " ", "_", ":", "_").Replace(v)
+}
+
+// sendGraphite sends metrics for the device at address to the Graphite server using the plaintext protocol
+func sendGraphite(ctx context.Context, address string, labels map[string]string, metrics map[string]float64) error {
+	now := time.Now().Unix()
+
+	var buf bytes.Buffer
+	for _, name := range sortedKeys(metrics) {
+		fmt.Fprintf(&buf, "%s %v %d\n", graphitePath(address, labels, name), metrics[name], now)
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", graphiteAddress)
+	if err != nil {
+		return fmt.Errorf("could not connect to graphite: %v", err)
+	}
+	defer conn.Close()
+
+	conn.SetWriteDeadline(time.Now().Add(timeout))
+
+	_, err = conn.Write(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("could not send metrics to graphite: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/choria-io/fisk"
+	"github.com/ripienaar/shellyctl"
+)
+
+var (
+	healthExpectedState string
+	healthPowerMin      float64
+	healthPowerMax      float64
+	healthMaxTemp       float64
+	healthMinRSSI       int
+)
+
+// healthCheck is the outcome of a single check, message describes the value that was checked
+type healthCheck struct {
+	name    string
+	ok      bool
+	message string
+}
+
+func healthAction(_ *fisk.ParseContext) error {
+	if healthPowerMax > 0 && healthPowerMin > healthPowerMax {
+		return fmt.Errorf("--expected-power-min must be below --expected-power-max")
+	}
+
+	return forEachDevice(healthDevice)
+}
+
+// healthDevice runs every check against the device and reports each, the device fails when any check fails
+func healthDevice(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+	status, err := plug.Status(ctx)
+	if err != nil {
+		fmt.Fprintf(w, "%s %s: %v\n", colorBad.Sprint("FAIL"), "Reachable", err)
+		return err
+	}
+
+	checks := append([]healthCheck{{"Reachable", true, "device responded"}}, healthChecks(status)...)
+
+	failed := 0
+	for _, check := range checks {
+		label := colorGood.Sprint("PASS")
+		if !check.ok {
+			label = colorBad.Sprint("FAIL")
+			failed++
+		}
+
+		fmt.Fprintf(w, "%s %s: %s\n", label, check.name, check.message)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d health checks failed", failed, len(checks))
+	}
+
+	return nil
+}
+
+// healthChecks checks the status against the thresholds, checks without a threshold are skipped
+func healthChecks(status *shellyctl.DeviceStatus) []healthCheck {
+	var checks []healthCheck
+
+	if len(status.Relays) > 0 {
+		relay := status.Relays[0]
+
+		if healthExpectedState != "" {
+			state := "off"
+			if relay.IsOn {
+				state = "on"
+			}
+			checks = append(checks, healthCheck{"Relay State", state == healthExpectedState, fmt.Sprintf("relay is %s, expected %s", state, healthExpectedState)})
+		}
+
+		checks = append(checks, healthCheck{"Overpower", !relay.Overpower, fmt.Sprintf("overpower protection triggered: %t", relay.Overpower)})
+	}
+
+	if (healthPowerMin > 0 || healthPowerMax > 0) && len(status.Meters) > 0 {
+		power := status.Meters[0].Power
+
+		check := healthCheck{name: "Power", ok: true, message: fmt.Sprintf("%.2f Watt", power)}
+		switch {
+		case power < healthPowerMin:
+			check.ok = false
+			check.message = fmt.Sprintf("%.2f Watt is below the minimum of %.2f Watt", power, healthPowerMin)
+		case healthPowerMax > 0 && power > healthPowerMax:
+			check.ok = false
+			check.message = fmt.Sprintf("%.2f Watt is above the maximum of %.2f Watt", power, healthPowerMax)
+		}
+		checks = append(checks, check)
+	}
+
+	if healthMaxTemp > 0 {
+		// sensors report the measured temperature in tmp, plugs report their internal temperature
+		temp := status.Temperature
+		if status.Tmp != nil && status.Temperature == 0 {
+			temp = status.Tmp.Celsius()
+		}
+
+		check := healthCheck{name: "Temperature", ok: temp <= healthMaxTemp && !status.OverTemperature, message: fmt.Sprintf("%.1f °C", temp)}
+		switch {
+		case status.OverTemperature:
+			check.message = fmt.Sprintf("%.1f °C, the device reports overheating", temp)
+		case !check.ok:
+			check.message = fmt.Sprintf("%.1f °C is above the maximum of %.1f °C", temp, healthMaxTemp)
+		}
+		checks = append(checks, check)
+	}
+
+	if healthMinRSSI < 0 {
+		rssi := status.WiFi.RSSI
+		check := healthCheck{name: "WiFi Signal", ok: rssi >= healthMinRSSI, message: fmt.Sprintf("%d dBm", rssi)}
+		if !check.ok {
+			check.message = fmt.Sprintf("%d dBm is below the minimum of %d dBm", rssi, healthMinRSSI)
+		}
+		checks = append(checks, check)
+	}
+
+	return checks
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ripienaar/shellyctl"
+)
+
+func TestHealthChecks(t *testing.T) {
+	t.Cleanup(func() {
+		healthExpectedState, healthPowerMin, healthPowerMax, healthMaxTemp, healthMinRSSI = "", 0, 0, 0, 0
+	})
+
+	status := &shellyctl.DeviceStatus{
+		WiFi:        shellyctl.WiFiStatus{RSSI: -82},
+		Relays:      []shellyctl.Relay{{IsOn: true}},
+		Meters:      []shellyctl.Meter{{Power: 120}},
+		Temperature: 45,
+	}
+
+	checks := healthChecks(status)
+	if len(checks) != 1 || checks[0].name != "Overpower" || !checks[0].ok {
+		t.Fatalf("expected only the overpower check without thresholds: %+v", checks)
+	}
+
+	healthExpectedState, healthPowerMin, healthPowerMax, healthMaxTemp, healthMinRSSI = "off", 10, 100, 50, -75
+
+	failed := map[string]bool{}
+	for _, check := range healthChecks(status) {
+		if !check.ok {
+			failed[check.name] = true
+		}
+	}
+	for _, name := range []string{"Relay State", "Power", "WiFi Signal"} {
+		if !failed[name] {
+			t.Errorf("expected %s to fail", name)
+		}
+	}
+	if len(failed) != 3 {
+		t.Errorf("unexpected failed checks: %v", failed)
+	}
+}
+
+func TestHealthDevice(t *testing.T) {
+	t.Cleanup(func() { healthExpectedState = "" })
+
+	plug := (&mockDevice{dir: "gen1"}).start(t, time.Second)
+
+	healthExpectedState = "on"
+	out := &bytes.Buffer{}
+	err := healthDevice(context.Background(), "plug", plug, out)
+	if err != nil {
+		t.Fatalf("health check failed: %v\n%s", err, out)
+	}
+
+	healthExpectedState = "off"
+	out.Reset()
+	err = healthDevice(context.Background(), "plug", plug, out)
+	if err == nil || !strings.Contains(out.String(), "relay is on, expected off") {
+		t.Fatalf("expected the relay state check to fail: %v\n%s", err, out)
+	}
+}
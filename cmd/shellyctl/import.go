@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"reflect"
+	"sort"
+
+	"github.com/choria-io/fisk"
+	"github.com/ripienaar/shellyctl"
+)
+
+var importFile string
+
+// importChange is a single setting that differs between the device and the imported document
+type importChange struct {
+	component string
+	key       string
+	from      any
+	to        any
+}
+
+func (c importChange) String() string {
+	from, _ := json.Marshal(c.from)
+	to, _ := json.Marshal(c.to)
+
+	if c.component == "" {
+		return fmt.Sprintf("%s: %s -> %s", c.key, from, to)
+	}
+
+	return fmt.Sprintf("%s %s: %s -> %s", c.component, c.key, from, to)
+}
+
+func importAction(_ *fisk.ParseContext) error {
+	return forEachDevice(importDevice)
+}
+
+func importDevice(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+	ej, err := os.ReadFile(importFile)
+	if err != nil {
+		return err
+	}
+
+	var export deviceExport
+	err = json.Unmarshal(ej, &export)
+	if err != nil {
+		return fmt.Errorf("invalid export in %s: %v", importFile, err)
+	}
+	if len(export.Config) == 0 {
+		return fmt.Errorf("no configuration found in %s", importFile)
+	}
+
+	nfo, err := plug.Info(ctx)
+	if err != nil {
+		return err
+	}
+
+	var changes []importChange
+	if nfo.Generation() == 1 {
+		current, err := plug.Settings(ctx)
+		if err != nil {
+			return err
+		}
+
+		changes = gen1ImportChanges(current, export.Config)
+	} else {
+		var current map[string]any
+		err = plug.RPC(ctx, "Shelly.GetConfig", nil, &current)
+		if err != nil {
+			return err
+		}
+
+		changes = gen2ImportChanges(current, export.Config)
+	}
+
+	if len(changes) == 0 {
+		fmt.Fprintf(w, "Configuration of %s matches %s\n", address, importFile)
+		return nil
+	}
+
+	fmt.Fprintf(w, "Changes to %s:\n", address)
+	fmt.Fprintln(w)
+	for _, change := range changes {
+		fmt.Fprintf(w, "  %s\n", change)
+	}
+	fmt.Fprintln(w)
+
+	if nfo.Generation() == 1 {
+		err = importGen1(ctx, plug, changes)
+	} else {
+		err = importGen2(ctx, plug, changes, w)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Applied %d changes from %s\n", len(changes), importFile)
+
+	return nil
+}
+
+// gen1ImportChanges finds settings in desired that differ from current, relays are compared individually
+func gen1ImportChanges(current map[string]any, desired map[string]any) []importChange {
+	changes := gen1SettingChanges("", current, desired, gen1ReadOnlySettings)
+
+	currentRelays, _ := current["relays"].([]any)
+	desiredRelays, _ := desired["relays"].([]any)
+
+	for i, r := range desiredRelays {
+		relay, ok := r.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		currentRelay := map[string]any{}
+		if i < len(currentRelays) {
+			currentRelay, _ = currentRelays[i].(map[string]any)
+		}
+
+		changes = append(changes, gen1SettingChanges(fmt.Sprintf("relay/%d", i), currentRelay, relay, gen1ReadOnlyRelaySettings)...)
+	}
+
+	return changes
+}
+
+func gen1SettingChanges(component string, current map[string]any, desired map[string]any, skip map[string]bool) []importChange {
+	want := gen1SettingValues(desired, skip)
+	have := gen1SettingValues(current, skip)
+
+	var changes []importChange
+	for _, key := range sortedKeys(want) {
+		if have[key] != want[key] {
+			changes = append(changes, importChange{component: component, key: key, from: current[key], to: desired[key]})
+		}
+	}
+
+	return changes
+}
+
+func importGen1(ctx context.Context, plug shellyctl.Plug, changes []importChange) error {
+	var components []string
+	settings := map[string]map[string]string{}
+
+	for _, change := range changes {
+		if settings[change.component] == nil {
+			settings[change.component] = map[string]string{}
+			components = append(components, change.component)
+		}
+
+		val, _ := gen1SettingValue(change.to)
+		settings[change.component][change.key] = val
+	}
+
+	for _, component := range components {
+		_, err := plug.UpdateSettings(ctx, component, settings[component])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// gen2ImportChanges finds the settings of every component in desired that differ from current
+func gen2ImportChanges(current map[string]any, desired map[string]any) []importChange {
+	var changes []importChange
+
+	for _, key := range sortedKeys(desired) {
+		if gen2SkipRestore[key] {
+			slog.Warn("Skipping component", "component", key)
+			continue
+		}
+
+		_, _, err := gen2ComponentMethod(key, "SetConfig")
+		if err != nil {
+			slog.Warn("Skipping component", "component", key, "error", err)
+			continue
+		}
+
+		want, ok := desired[key].(map[string]any)
+		if !ok {
+			continue
+		}
+		have, _ := current[key].(map[string]any)
+
+		// these are reported by the device but cannot be set
+		for _, cfg := range []map[string]any{want, have} {
+			if device, ok := cfg["device"].(map[string]any); ok && key == "sys" {
+				delete(device, "mac")
+				delete(device, "fw_id")
+			}
+		}
+
+		for _, setting := range sortedKeys(want) {
+			if setting == "id" {
+				continue
+			}
+
+			if !reflect.DeepEqual(have[setting], want[setting]) {
+				changes = append(changes, importChange{component: key, key: setting, from: have[setting], to: want[setting]})
+			}
+		}
+	}
+
+	return changes
+}
+
+func importGen2(ctx context.Context, plug shellyctl.Plug, changes []importChange, w io.Writer) error {
+	var components []string
+	configs := map[string]map[string]any{}
+
+	for _, change := range changes {
+		if configs[change.component] == nil {
+			configs[change.component] = map[string]any{}
+			components = append(components, change.component)
+		}
+
+		configs[change.component][change.key] = change.to
+	}
+
+	restart := false
+	for _, component := range components {
+		method, id, err := gen2ComponentMethod(component, "SetConfig")
+		if err != nil {
+			return err
+		}
+
+		params := map[string]any{"config": configs[component]}
+		if id >= 0 {
+			params["id"] = id
+		}
+
+		var res struct {
+			RestartRequired bool `json:"restart_required"`
+		}
+		err = plug.RPC(ctx, method, params, &res)
+		if err != nil {
+			return fmt.Errorf("%s failed: %v", method, err)
+		}
+
+		restart = restart || res.RestartRequired
+	}
+
+	if restart {
+		fmt.Fprintln(w, "The device must be restarted for all settings to take effect")
+	}
+
+	return nil
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ripienaar/shellyctl"
+)
+
+func TestImportDryRun(t *testing.T) {
+	ctx := context.Background()
+
+	defer func(file string) { importFile = file }(importFile)
+	importFile = filepath.Join(t.TempDir(), "plug.json")
+	err := os.WriteFile(importFile, []byte(`{"config":{"name":"Kitchen","relays":[{"auto_off":3600}]}}`), 0600)
+	if err != nil {
+		t.Fatalf("could not write export: %v", err)
+	}
+
+	srv := httptest.NewServer(newSimulatedPlug())
+	defer srv.Close()
+
+	dryRunOut := &bytes.Buffer{}
+	plug, err := shellyctl.NewPlug(url.URL{Scheme: "http", Host: srv.Listener.Addr().String()}, shellyctl.WithDryRun(dryRunOut))
+	if err != nil {
+		t.Fatalf("could not create plug: %v", err)
+	}
+
+	err = importDevice(ctx, "192.168.1.11", plug, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+
+	for _, req := range []string{"/settings?name=Kitchen", "/settings/relay/0?auto_off=3600"} {
+		if !strings.Contains(dryRunOut.String(), "DRY RUN: GET "+srv.URL+req) {
+			t.Fatalf("expected a request to %s:\n%s", req, dryRunOut)
+		}
+	}
+
+	settings, _ := plug.Settings(ctx)
+	if settings["name"] != "Simulated Plug" {
+		t.Fatalf("dry run changed the device: %v", settings["name"])
+	}
+}
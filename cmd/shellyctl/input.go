@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/choria-io/fisk"
+	"github.com/ripienaar/shellyctl"
+)
+
+var (
+	inputID   int
+	inputType string
+)
+
+func inputStatusAction(_ *fisk.ParseContext) error {
+	return forEachDevice(inputStatusDevice)
+}
+
+func inputStatusDevice(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+	_, err := requireGen2(ctx, plug)
+	if err != nil {
+		return err
+	}
+
+	var status shellyctl.Gen2InputStatus
+	err = plug.RPC(ctx, "Input.GetStatus", map[string]any{"id": inputID}, &status)
+	if err != nil {
+		return err
+	}
+
+	if status.State == nil {
+		fmt.Fprintf(w, "Input %d on %s is a button without a state\n", inputID, address)
+		return nil
+	}
+
+	fmt.Fprintf(w, "Input %d on %s: %s\n", inputID, address, onOffString(*status.State))
+
+	return nil
+}
+
+func inputConfigGetAction(_ *fisk.ParseContext) error {
+	return forEachDevice(inputConfigGetDevice)
+}
+
+func inputConfigGetDevice(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+	_, err := requireGen2(ctx, plug)
+	if err != nil {
+		return err
+	}
+
+	var cfg shellyctl.Gen2InputConfig
+	err = plug.RPC(ctx, "Input.GetConfig", map[string]any{"id": inputID}, &cfg)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Input %d configuration for %s\n", inputID, address)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "                Type: %s\n", cfg.Type)
+	fmt.Fprintf(w, "                Name: %s\n", cfg.Name)
+	fmt.Fprintf(w, "              Invert: %t\n", cfg.Invert)
+
+	// devices without a switch, like the input only devices, fail here
+	var sw shellyctl.Gen2SwitchConfig
+	err = plug.RPC(ctx, "Switch.GetConfig", map[string]any{"id": inputID}, &sw)
+	if err == nil {
+		fmt.Fprintf(w, "   Switch Input Mode: %s\n", sw.InMode)
+	}
+
+	return nil
+}
+
+func inputConfigSetAction(_ *fisk.ParseContext) error {
+	return forEachDevice(inputConfigSetDevice)
+}
+
+func inputConfigSetDevice(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+	_, err := requireGen2(ctx, plug)
+	if err != nil {
+		return err
+	}
+
+	// switch and button are input types while detached and activated are modes of the switch the input controls
+	switch inputType {
+	case "switch", "button":
+		err = plug.RPC(ctx, "Input.SetConfig", map[string]any{"id": inputID, "config": map[string]any{"type": inputType}}, &map[string]any{})
+	case "detached":
+		err = plug.RPC(ctx, "Switch.SetConfig", map[string]any{"id": inputID, "config": map[string]any{"in_mode": "detached"}}, &map[string]any{})
+	case "activated":
+		err = plug.RPC(ctx, "Switch.SetConfig", map[string]any{"id": inputID, "config": map[string]any{"in_mode": "activate"}}, &map[string]any{})
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Input %d on %s configured as %s\n", inputID, address, inputType)
+
+	return nil
+}
@@ -0,0 +1,550 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/ripienaar/shellyctl"
+)
+
+var updateGolden = flag.Bool("update", false, "Updates the golden files in testdata/output")
+
+// mockDevice serves the Shelly API using the responses stored in testdata/<dir>, the file for a request
+// is named after its path with / replaced by _ and the value of the turn query appended
+type mockDevice struct {
+	dir      string
+	user     string
+	pass     string
+	delay    time.Duration
+	failures int64
+	files    map[string]string
+
+	requests atomic.Int64
+}
+
+func (m *mockDevice) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	count := m.requests.Add(1)
+
+	if m.user != "" {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != m.user || pass != m.pass {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}
+
+	if count <= m.failures {
+		http.Error(w, "device is starting", http.StatusServiceUnavailable)
+		return
+	}
+
+	if m.delay > 0 {
+		select {
+		case <-time.After(m.delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	name := strings.ReplaceAll(strings.Trim(r.URL.Path, "/"), "/", "_")
+	if turn := r.URL.Query().Get("turn"); turn != "" {
+		name += "_" + turn
+	}
+	if file, ok := m.files[name]; ok {
+		name = file
+	}
+
+	body, err := os.ReadFile(filepath.Join("testdata", m.dir, name+".json"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+// start serves the device until the test completes and returns a plug connected to it
+func (m *mockDevice) start(t testing.TB, timeout time.Duration) shellyctl.Plug {
+	t.Helper()
+
+	srv := httptest.NewServer(m)
+	t.Cleanup(srv.Close)
+
+	address := url.URL{Scheme: "http", Host: srv.Listener.Addr().String()}
+	if m.user != "" {
+		address.User = url.UserPassword(m.user, m.pass)
+	}
+
+	plug, err := shellyctl.NewPlug(address, shellyctl.WithTimeout(timeout))
+	if err != nil {
+		t.Fatalf("could not create plug: %v", err)
+	}
+
+	return plug
+}
+
+// resetOutputFlags restores the output related flags once the test completes
+func resetOutputFlags(t testing.TB) {
+	t.Helper()
+
+	json, compact, choria, format, tmpl, jq, jp, lbls := jsonFormat, jsonCompact, choriaFormat, outputFormat, outputTemplate, outputJQ, outputJSONPath, labels
+	noColor, loc := color.NoColor, displayLocation
+
+	t.Cleanup(func() {
+		jsonFormat, jsonCompact, choriaFormat, outputFormat, outputTemplate, outputJQ, outputJSONPath, labels = json, compact, choria, format, tmpl, jq, jp, lbls
+		color.NoColor, displayLocation = noColor, loc
+	})
+
+	color.NoColor = true
+	displayLocation = time.UTC
+}
+
+// openDevNull opens a file to use as output in tests that do not check it
+func openDevNull() (*os.File, error) {
+	return os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+}
+
+// checkGolden compares out with testdata/output/name.golden, run the tests with -update to rewrite the file
+func checkGolden(t *testing.T, name string, out []byte) {
+	t.Helper()
+
+	file := filepath.Join("testdata", "output", name+".golden")
+
+	if *updateGolden {
+		err := os.WriteFile(file, out, 0644)
+		if err != nil {
+			t.Fatalf("could not update %s: %v", file, err)
+		}
+	}
+
+	expected, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("could not read %s: %v", file, err)
+	}
+
+	if !bytes.Equal(out, expected) {
+		t.Errorf("output does not match %s\nexpected:\n%s\ngot:\n%s", file, expected, out)
+	}
+}
+
+func TestTurnOnOff(t *testing.T) {
+	dev := &mockDevice{dir: "gen1"}
+	plug := dev.start(t, time.Second)
+
+	relay, err := plug.TurnOn(context.Background())
+	if err != nil {
+		t.Fatalf("turning on failed: %v", err)
+	}
+	if !relay.IsOn {
+		t.Fatalf("expected the relay to be on")
+	}
+
+	relay, err = plug.TurnOff(context.Background())
+	if err != nil {
+		t.Fatalf("turning off failed: %v", err)
+	}
+	if relay.IsOn {
+		t.Fatalf("expected the relay to be off")
+	}
+
+	stuck := &mockDevice{dir: "gen1", files: map[string]string{"relay_0_on": "relay_0_off"}}
+	_, err = stuck.start(t, time.Second).TurnOn(context.Background())
+	if !errors.Is(err, shellyctl.ErrRelayNotOn) {
+		t.Fatalf("expected %v got %v", shellyctl.ErrRelayNotOn, err)
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	dev := &mockDevice{dir: "gen1", user: "admin", pass: "secret"}
+	plug := dev.start(t, time.Second)
+
+	_, err := plug.Status(context.Background())
+	if err != nil {
+		t.Fatalf("request with valid credentials failed: %v", err)
+	}
+
+	dev = &mockDevice{dir: "gen1", user: "admin", pass: "secret"}
+	srv := httptest.NewServer(dev)
+	defer srv.Close()
+
+	plug, err = shellyctl.NewPlug(url.URL{Scheme: "http", Host: srv.Listener.Addr().String(), User: url.UserPassword("admin", "wrong")}, shellyctl.WithTimeout(time.Second))
+	if err != nil {
+		t.Fatalf("could not create plug: %v", err)
+	}
+
+	_, err = plug.Status(context.Background())
+	if !errors.Is(err, shellyctl.ErrAuthFailed) {
+		t.Fatalf("expected %v got %v", shellyctl.ErrAuthFailed, err)
+	}
+	if exitCodeFor(err) != exitAuthFailed {
+		t.Fatalf("expected exit code %d got %d", exitAuthFailed, exitCodeFor(err))
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	dev := &mockDevice{dir: "gen1", delay: time.Second}
+	plug := dev.start(t, 100*time.Millisecond)
+
+	start := time.Now()
+	_, err := plug.Status(context.Background())
+	if !errors.Is(err, shellyctl.ErrDeviceUnreachable) {
+		t.Fatalf("expected %v got %v", shellyctl.ErrDeviceUnreachable, err)
+	}
+	if time.Since(start) > 900*time.Millisecond {
+		t.Fatalf("request was not stopped after the timeout")
+	}
+}
+
+func TestMalformedResponse(t *testing.T) {
+	dev := &mockDevice{dir: "malformed"}
+	plug := dev.start(t, time.Second)
+
+	_, err := plug.Status(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "invalid response body") {
+		t.Fatalf("expected an invalid response body error got %v", err)
+	}
+}
+
+func TestGen2RPC(t *testing.T) {
+	dev := &mockDevice{dir: "gen2"}
+	plug := dev.start(t, time.Second)
+
+	var out bytes.Buffer
+	err := ecoModeGetDevice(context.Background(), "192.168.1.11", plug, &out)
+	if err != nil {
+		t.Fatalf("eco-mode get failed: %v", err)
+	}
+	if out.String() != "Eco mode on 192.168.1.11: On\n" {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+
+	_, err = requireGen1(context.Background(), plug)
+	if err == nil {
+		t.Fatalf("expected a Gen 2 device to be rejected")
+	}
+}
+
+func TestWaitForPlugRetries(t *testing.T) {
+	dev := &mockDevice{dir: "gen1", failures: 2}
+	plug := dev.start(t, time.Second)
+
+	err := waitForPlug(context.Background(), plug, 5*time.Second)
+	if err != nil {
+		t.Fatalf("waiting for the device failed: %v", err)
+	}
+	if dev.requests.Load() != 3 {
+		t.Fatalf("expected 3 requests got %d", dev.requests.Load())
+	}
+
+	dev = &mockDevice{dir: "gen1", failures: 1000}
+	plug = dev.start(t, time.Second)
+
+	err = waitForPlug(context.Background(), plug, 500*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "did not become reachable") {
+		t.Fatalf("expected the device to not become reachable got %v", err)
+	}
+}
+
+func TestEnergyOutput(t *testing.T) {
+	cases := []struct {
+		name  string
+		setup func()
+	}{
+		{"energy_text", func() {}},
+		// tables are only shown on a terminal
+		{"energy_text", func() { outputFormat = "table" }},
+		{"energy_json", func() { jsonFormat = true }},
+		{"energy_json_compact", func() { jsonFormat, jsonCompact = true, true }},
+		{"energy_choria", func() { choriaFormat = true; labels = map[string]string{"room": "office"} }},
+		{"energy_jq", func() { jsonFormat, outputJQ = true, ".power_watt" }},
+		{"energy_json_path", func() { jsonFormat, outputJSONPath = true, ".power_watt" }},
+		{"energy_template", func() { outputTemplate = "{{.Address}} {{(index .Status.Meters 0).Power}}" }},
+	}
+
+	dev := &mockDevice{dir: "gen1"}
+	plug := dev.start(t, time.Second)
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			resetOutputFlags(t)
+			c.setup()
+
+			var out bytes.Buffer
+			err := energyDevice(context.Background(), "192.168.1.10", plug, &out)
+			if err != nil {
+				t.Fatalf("energy failed: %v", err)
+			}
+
+			checkGolden(t, c.name, out.Bytes())
+		})
+	}
+}
+
+// TestV1EnergyConversion guards the unit of the Gen 1 meter total, the API documents it in Watt-minutes and not
+// Wh (https://shelly-api-docs.shelly.cloud/gen1/#shelly-plug-plugs-status) so 60000 is 1 kWh and not 60 kWh
+func TestV1EnergyConversion(t *testing.T) {
+	resetOutputFlags(t)
+
+	plug := (&mockDevice{dir: "gen1", files: map[string]string{"status": "status_60000wm"}}).start(t, time.Second)
+
+	var out bytes.Buffer
+	err := energyDevice(context.Background(), "192.168.1.10", plug, &out)
+	if err != nil {
+		t.Fatalf("energy failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "Total Consumption: 1.00 kWh") {
+		t.Fatalf("expected 60000 Watt-minutes to be shown as 1 kWh:\n%s", out.String())
+	}
+
+	jsonFormat = true
+	out.Reset()
+	err = energyDevice(context.Background(), "192.168.1.10", plug, &out)
+	if err != nil {
+		t.Fatalf("energy failed: %v", err)
+	}
+
+	var reading map[string]float64
+	err = json.Unmarshal(out.Bytes(), &reading)
+	if err != nil {
+		t.Fatalf("invalid JSON output: %v", err)
+	}
+	if reading["power_total_kwh"] != 1 {
+		t.Fatalf("expected 60000 Watt-minutes to be 1 kWh got %v", reading["power_total_kwh"])
+	}
+}
+
+func TestInfoOutput(t *testing.T) {
+	cases := []struct {
+		name  string
+		setup func()
+	}{
+		{"info_text", func() {}},
+		{"info_json", func() { jsonFormat = true }},
+		{"info_jq", func() { jsonFormat, outputJQ = true, ".info.type" }},
+		{"info_json_path", func() { jsonFormat, outputJSONPath = true, ".status.meters[0].power" }},
+		{"info_template", func() { outputTemplate = "{{.Info.Type}} {{.Info.FW}} {{.Status.WiFi.SSID}}" }},
+	}
+
+	dev := &mockDevice{dir: "gen1"}
+	plug := dev.start(t, time.Second)
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			resetOutputFlags(t)
+			c.setup()
+
+			var out bytes.Buffer
+			var updates atomic.Bool
+			err := infoDevice(context.Background(), "192.168.1.10", plug, &updates, &out)
+			if err != nil {
+				t.Fatalf("info failed: %v", err)
+			}
+
+			checkGolden(t, c.name, out.Bytes())
+		})
+	}
+}
+
+func TestDimmer(t *testing.T) {
+	dev := &mockDevice{dir: "dimmer"}
+	srv := httptest.NewServer(dev)
+	defer srv.Close()
+
+	address := url.URL{Scheme: "http", Host: srv.Listener.Addr().String()}
+	opts := []shellyctl.Option{shellyctl.WithTimeout(time.Second)}
+
+	plug, err := shellyctl.NewPlug(address, opts...)
+	if err != nil {
+		t.Fatalf("could not create plug: %v", err)
+	}
+
+	plug, err = detectDevice(context.Background(), address, plug, opts)
+	if err != nil {
+		t.Fatalf("detecting the device failed: %v", err)
+	}
+	if _, ok := plug.(shellyctl.Dimmer); !ok {
+		t.Fatalf("expected a dimmer to be detected")
+	}
+
+	relay, err := plug.TurnOn(context.Background())
+	if err != nil {
+		t.Fatalf("turning on failed: %v", err)
+	}
+	if !relay.IsOn {
+		t.Fatalf("expected the light to be on")
+	}
+
+	var out bytes.Buffer
+	dimmerBrightness = 75
+	err = brightnessSetDevice(context.Background(), "192.168.1.12", plug, &out)
+	if err != nil {
+		t.Fatalf("setting the brightness failed: %v", err)
+	}
+	if out.String() != "Brightness on 192.168.1.12 set to 75%\n" {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+
+	_, err = requireDimmer(context.Background(), (&mockDevice{dir: "gen1"}).start(t, time.Second))
+	if err == nil {
+		t.Fatalf("expected a plug to be rejected")
+	}
+}
+
+func TestRoller(t *testing.T) {
+	cases := []struct {
+		dir string
+		out string
+	}{
+		{"roller", "Device 192.168.1.13 moving to 25%, currently at 40%\n"},
+		{"cover", "Device 192.168.1.13 moving to 25%, currently at 62%\n"},
+	}
+
+	defer func(p int) { rollerPosition = p }(rollerPosition)
+	rollerPosition = 25
+
+	for _, c := range cases {
+		t.Run(c.dir, func(t *testing.T) {
+			srv := httptest.NewServer(&mockDevice{dir: c.dir})
+			defer srv.Close()
+
+			address := url.URL{Scheme: "http", Host: srv.Listener.Addr().String()}
+			opts := []shellyctl.Option{shellyctl.WithTimeout(time.Second)}
+
+			plug, err := shellyctl.NewPlug(address, opts...)
+			if err != nil {
+				t.Fatalf("could not create plug: %v", err)
+			}
+
+			plug, err = detectDevice(context.Background(), address, plug, opts)
+			if err != nil {
+				t.Fatalf("detecting the device failed: %v", err)
+			}
+
+			roller, err := requireRoller(context.Background(), plug)
+			if err != nil {
+				t.Fatalf("expected a roller to be detected: %v", err)
+			}
+
+			var out bytes.Buffer
+			err = rollerPositionDevice(context.Background(), "192.168.1.13", roller, &out)
+			if err != nil {
+				t.Fatalf("setting the position failed: %v", err)
+			}
+			if out.String() != c.out {
+				t.Fatalf("unexpected output %q", out.String())
+			}
+
+			state, err := roller.RollerStatus(context.Background())
+			if err != nil {
+				t.Fatalf("retrieving the state failed: %v", err)
+			}
+			if !state.Moving() {
+				t.Fatalf("expected the roller to be moving")
+			}
+		})
+	}
+}
+
+func TestSensor(t *testing.T) {
+	resetOutputFlags(t)
+
+	srv := httptest.NewServer(&mockDevice{dir: "ht"})
+	defer srv.Close()
+
+	address := url.URL{Scheme: "http", Host: srv.Listener.Addr().String()}
+	opts := []shellyctl.Option{shellyctl.WithTimeout(time.Second)}
+
+	plug, err := shellyctl.NewPlug(address, opts...)
+	if err != nil {
+		t.Fatalf("could not create plug: %v", err)
+	}
+
+	plug, err = detectDevice(context.Background(), address, plug, opts)
+	if err != nil {
+		t.Fatalf("detecting the device failed: %v", err)
+	}
+
+	_, err = plug.TurnOn(context.Background())
+	if !errors.Is(err, shellyctl.ErrUnsupported) {
+		t.Fatalf("expected %v got %v", shellyctl.ErrUnsupported, err)
+	}
+
+	var out bytes.Buffer
+	err = temperatureDevice(context.Background(), "192.168.1.14", plug, &out)
+	if err != nil {
+		t.Fatalf("temperature failed: %v", err)
+	}
+
+	expected := "Sensor information for 192.168.1.14\n\n         Temperature: 22.5 °C\n            Humidity: 48.5 %\n             Battery: 87 %\n"
+	if out.String() != expected {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+
+	out.Reset()
+	jsonFormat, jsonCompact = true, true
+	err = energyDevice(context.Background(), "192.168.1.14", plug, &out)
+	if err != nil {
+		t.Fatalf("energy failed: %v", err)
+	}
+	if out.String() != "{\"battery_percent\":87}\n" {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+}
+
+func TestScheduleOnce(t *testing.T) {
+	resetOutputFlags(t)
+
+	defer func(in time.Duration, action string) { scheduleIn, scheduleAction = in, action }(scheduleIn, scheduleAction)
+	scheduleIn, scheduleAction = 2*time.Hour, "off"
+
+	var out bytes.Buffer
+	plug := (&mockDevice{dir: "gen2"}).start(t, time.Second)
+	err := scheduleOnceDevice(context.Background(), "192.168.1.11", plug, &out)
+	if err != nil {
+		t.Fatalf("scheduling failed: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "Device 192.168.1.11 will turn Off at ") || !strings.HasSuffix(out.String(), " using schedule 3\n") {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+
+	out.Reset()
+	plug = (&mockDevice{dir: "gen1", files: map[string]string{"relay_0_on": "relay_0_timer"}}).start(t, time.Second)
+	err = scheduleOnceDevice(context.Background(), "192.168.1.10", plug, &out)
+	if err != nil {
+		t.Fatalf("scheduling failed: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "Device 192.168.1.10 turned On, it will turn Off at ") {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+}
+
+func TestConfigGetCompact(t *testing.T) {
+	resetOutputFlags(t)
+
+	defer func(component string, id int) { configComponent, configID = component, id }(configComponent, configID)
+	configComponent, configID, jsonCompact = "sys", 0, true
+
+	var out bytes.Buffer
+	plug := (&mockDevice{dir: "gen2"}).start(t, time.Second)
+	err := configGetDevice(context.Background(), "192.168.1.11", plug, &out)
+	if err != nil {
+		t.Fatalf("config get failed: %v", err)
+	}
+	if strings.Count(out.String(), "\n") != 1 || !strings.HasPrefix(out.String(), `{"cfg_rev":12,`) {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/itchyny/gojq"
+)
+
+var outputJQ string
+
+// writeJQ writes every result of the --output-jq expression applied to v
+func writeJQ(w io.Writer, v any) error {
+	query, err := gojq.Parse(outputJQ)
+	if err != nil {
+		return fmt.Errorf("invalid jq expression: %v", err)
+	}
+
+	// gojq only supports the types produced by encoding/json
+	j, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	var input any
+	err = json.Unmarshal(j, &input)
+	if err != nil {
+		return err
+	}
+
+	iter := query.Run(input)
+	for {
+		res, ok := iter.Next()
+		if !ok {
+			break
+		}
+
+		if err, ok := res.(error); ok {
+			return fmt.Errorf("jq expression failed: %v", err)
+		}
+
+		j, err := marshalOutput(res)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(w, string(j))
+	}
+
+	return nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var outputJSONPath string
+
+// parseJSONPath splits a path like .meters[0].power into map keys and list indexes, the leading dot is optional
+// and . alone refers to the whole document
+func parseJSONPath(expr string) ([]any, error) {
+	path := strings.TrimPrefix(strings.TrimSpace(expr), ".")
+	if path == "" {
+		return nil, nil
+	}
+
+	var res []any
+	for _, part := range strings.Split(path, ".") {
+		key, rest, _ := strings.Cut(part, "[")
+		if key == "" && rest == "" {
+			return nil, fmt.Errorf("invalid --json-path %q: empty key", expr)
+		}
+		if key != "" {
+			res = append(res, key)
+		}
+
+		for rest != "" {
+			idx, after, ok := strings.Cut(rest, "]")
+			if !ok {
+				return nil, fmt.Errorf("invalid --json-path %q: missing ]", expr)
+			}
+
+			i, err := strconv.Atoi(idx)
+			if err != nil || i < 0 {
+				return nil, fmt.Errorf("invalid --json-path %q: invalid index %q", expr, idx)
+			}
+			res = append(res, i)
+
+			if after == "" {
+				break
+			}
+			if !strings.HasPrefix(after, "[") {
+				return nil, fmt.Errorf("invalid --json-path %q: unexpected %q after index", expr, after)
+			}
+			rest = after[1:]
+		}
+	}
+
+	return res, nil
+}
+
+// lookupJSONPath finds the value at path in data as produced by encoding/json
+func lookupJSONPath(data any, expr string) (any, error) {
+	path, err := parseJSONPath(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	walked := ""
+	for _, step := range path {
+		switch s := step.(type) {
+		case string:
+			m, ok := data.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("--json-path %q: %s is not an object", expr, jsonPathName(walked))
+			}
+			data, ok = m[s]
+			if !ok {
+				return nil, fmt.Errorf("--json-path %q: %s has no key %q", expr, jsonPathName(walked), s)
+			}
+			walked += "." + s
+
+		case int:
+			l, ok := data.([]any)
+			if !ok {
+				return nil, fmt.Errorf("--json-path %q: %s is not a list", expr, jsonPathName(walked))
+			}
+			if s >= len(l) {
+				return nil, fmt.Errorf("--json-path %q: index %d is out of range, %s has %d items", expr, s, jsonPathName(walked), len(l))
+			}
+			data = l[s]
+			walked += fmt.Sprintf("[%d]", s)
+		}
+	}
+
+	return data, nil
+}
+
+func jsonPathName(walked string) string {
+	if walked == "" {
+		return "the document"
+	}
+
+	return walked
+}
+
+// writeJSONPath writes the value at the --json-path expression in v, scalars are written bare and objects and lists
+// as JSON
+func writeJSONPath(w io.Writer, v any) error {
+	// paths are resolved using the types produced by encoding/json
+	j, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	var input any
+	err = json.Unmarshal(j, &input)
+	if err != nil {
+		return err
+	}
+
+	res, err := lookupJSONPath(input, outputJSONPath)
+	if err != nil {
+		return err
+	}
+
+	switch val := res.(type) {
+	case string:
+		fmt.Fprintln(w, val)
+	case float64:
+		fmt.Fprintln(w, strconv.FormatFloat(val, 'f', -1, 64))
+	case bool:
+		fmt.Fprintln(w, strconv.FormatBool(val))
+	case nil:
+		fmt.Fprintln(w, "null")
+	default:
+		j, err := marshalOutput(val)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, string(j))
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestLookupJSONPath(t *testing.T) {
+	var doc any
+	json.Unmarshal([]byte(`{"switch":{"output":true},"meters":[{"power":2.5},{"power":7}],"grid":[[1,2],[3,4]]}`), &doc)
+
+	cases := []struct {
+		path     string
+		expected any
+		err      string
+	}{
+		{".switch.output", true, ""},
+		{"switch.output", true, ""},
+		{".meters[1].power", 7.0, ""},
+		{".grid[1][0]", 3.0, ""},
+		{".meters[2].power", nil, "index 2 is out of range, .meters has 2 items"},
+		{".switch.missing", nil, `.switch has no key "missing"`},
+		{".switch[0]", nil, ".switch is not a list"},
+		{".meters.power", nil, ".meters is not an object"},
+		{".meters[x]", nil, `invalid index "x"`},
+		{".meters[0", nil, "missing ]"},
+		{".switch..output", nil, "empty key"},
+	}
+
+	for _, c := range cases {
+		res, err := lookupJSONPath(doc, c.path)
+		switch {
+		case c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err)):
+			t.Errorf("%s: expected error %q got %v", c.path, c.err, err)
+		case c.err == "" && err != nil:
+			t.Errorf("%s: unexpected error: %v", c.path, err)
+		case c.err == "" && res != c.expected:
+			t.Errorf("%s: expected %v got %v", c.path, c.expected, res)
+		}
+	}
+
+	res, err := lookupJSONPath(doc, ".")
+	if err != nil || res == nil {
+		t.Errorf("expected . to return the document: %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/ripienaar/shellyctl"
+)
+
+// labelsValue is a fisk value that accepts key=value pairs and also comma separated
+// lists of pairs as used in the SHELLYCTL_LABELS environment variable
+type labelsValue map[string]string
+
+var (
+	labelSplitRegex = regexp.MustCompile("[:=]")
+
+	// deviceLabels adds the device MAC address and address as labels to metric outputs
+	deviceLabels bool
+
+	// deviceMACs holds the MAC address of every device seen so labels stay the same while a device is unreachable
+	deviceMACs sync.Map
+)
+
+// metricLabels are the labels for metrics of the device at address, the --label values and, when --device-label
+// is set, device holding the MAC address and ip holding the address
+func metricLabels(ctx context.Context, address string, plug shellyctl.Plug) map[string]string {
+	res := make(map[string]string, len(labels)+2)
+	for k, v := range labels {
+		res[k] = v
+	}
+
+	if !deviceLabels {
+		return res
+	}
+
+	res["ip"] = address
+
+	nfo, err := plug.Info(ctx)
+	if err == nil {
+		deviceMACs.Store(address, nfo.MAC)
+	} else {
+		slog.Debug("Could not determine the device MAC address", "device", address, "error", err)
+	}
+
+	mac, _ := deviceMACs.Load(address)
+	res["device"], _ = mac.(string)
+
+	return res
+}
+
+func (l *labelsValue) Set(value string) error {
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		parts := labelSplitRegex.Split(pair, 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("expected KEY=VALUE got '%s'", pair)
+		}
+
+		(*l)[parts[0]] = parts[1]
+	}
+
+	return nil
+}
+
+func (l *labelsValue) String() string {
+	return fmt.Sprintf("%s", map[string]string(*l))
+}
+
+func (l *labelsValue) IsCumulative() bool {
+	return true
+}
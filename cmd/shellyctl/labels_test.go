@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMetricLabels(t *testing.T) {
+	t.Cleanup(func() {
+		labels, deviceLabels = map[string]string{}, false
+		deviceMACs.Delete("192.168.1.10")
+	})
+
+	plug := (&mockDevice{dir: "gen1"}).start(t, time.Second)
+	nfo, err := plug.Info(context.Background())
+	if err != nil {
+		t.Fatalf("info failed: %v", err)
+	}
+
+	labels = map[string]string{"room": "kitchen"}
+
+	res := metricLabels(context.Background(), "192.168.1.10", plug)
+	if !reflect.DeepEqual(res, map[string]string{"room": "kitchen"}) {
+		t.Fatalf("unexpected labels: %v", res)
+	}
+
+	deviceLabels = true
+
+	res = metricLabels(context.Background(), "192.168.1.10", plug)
+	expected := map[string]string{"room": "kitchen", "ip": "192.168.1.10", "device": nfo.MAC}
+	if !reflect.DeepEqual(res, expected) {
+		t.Fatalf("expected %v got %v", expected, res)
+	}
+
+	newExporterMetrics()
+	promUp.With(exporterLabels(context.Background(), "192.168.1.10", plug)).Set(1)
+}
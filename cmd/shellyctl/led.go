@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/choria-io/fisk"
+	"github.com/ripienaar/shellyctl"
+)
+
+var (
+	ledMode       string
+	ledBrightness int
+)
+
+// getLEDConfig retrieves the LED configuration of a Gen 2 plug, plugs manage LEDs using the PLUGS_UI component
+func getLEDConfig(ctx context.Context, plug shellyctl.Plug) (map[string]any, error) {
+	_, err := requireGen2(ctx, plug)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg map[string]any
+	err = plug.RPC(ctx, "PLUGS_UI.GetConfig", nil, &cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	leds, ok := cfg["leds"].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("device does not support LED configuration")
+	}
+
+	return leds, nil
+}
+
+func setLEDConfig(ctx context.Context, plug shellyctl.Plug, leds map[string]any) error {
+	return plug.RPC(ctx, "PLUGS_UI.SetConfig", map[string]any{"config": map[string]any{"leds": leds}}, &map[string]any{})
+}
+
+func ledShowAction(_ *fisk.ParseContext) error {
+	return forEachDevice(ledShowDevice)
+}
+
+func ledShowDevice(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+	leds, err := getLEDConfig(ctx, plug)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "LED configuration for %s\n", address)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "                Mode: %v\n", leds["mode"])
+
+	if brightness, ok := lookupPath(leds, "colors.power.brightness"); ok {
+		fmt.Fprintf(w, "          Brightness: %v%%\n", brightness)
+	}
+
+	if enabled, ok := lookupPath(leds, "night_mode.enable"); ok {
+		fmt.Fprintf(w, "          Night Mode: %v\n", enabled)
+		if brightness, ok := lookupPath(leds, "night_mode.brightness"); ok && enabled == true {
+			fmt.Fprintf(w, "    Night Brightness: %v%%\n", brightness)
+		}
+	}
+
+	return nil
+}
+
+func ledSetAction(_ *fisk.ParseContext) error {
+	return forEachDevice(ledSetDevice)
+}
+
+func ledSetDevice(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+	leds, err := getLEDConfig(ctx, plug)
+	if err != nil {
+		return err
+	}
+
+	night, _ := leds["night_mode"].(map[string]any)
+	if night == nil {
+		night = map[string]any{}
+	}
+
+	if ledMode == "night" {
+		night["enable"] = true
+	} else {
+		leds["mode"] = ledMode
+		night["enable"] = false
+	}
+	leds["night_mode"] = night
+
+	err = setLEDConfig(ctx, plug, leds)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "LED mode on %s set to %s\n", address, ledMode)
+
+	return nil
+}
+
+func ledBrightnessAction(_ *fisk.ParseContext) error {
+	if ledBrightness < 0 || ledBrightness > 100 {
+		return fmt.Errorf("brightness must be between 0 and 100")
+	}
+
+	return forEachDevice(ledBrightnessDevice)
+}
+
+func ledBrightnessDevice(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+	leds, err := getLEDConfig(ctx, plug)
+	if err != nil {
+		return err
+	}
+
+	colors, ok := leds["colors"].(map[string]any)
+	if !ok {
+		return fmt.Errorf("device does not support setting the LED brightness")
+	}
+
+	// colors holds entries like power and switch:0 with either a brightness or on and off states with brightness
+	for _, c := range colors {
+		color, ok := c.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		if _, ok := color["brightness"]; ok {
+			color["brightness"] = ledBrightness
+		}
+
+		for _, state := range []string{"on", "off"} {
+			if s, ok := color[state].(map[string]any); ok {
+				s["brightness"] = ledBrightness
+			}
+		}
+	}
+
+	err = setLEDConfig(ctx, plug, leds)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "LED brightness on %s set to %d%%\n", address, ledBrightness)
+
+	return nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+
+	"github.com/choria-io/fisk"
+)
+
+var logLevel string
+
+// configureLogging sets up the default slog logger to write to stderr at the level set using --log-level
+func configureLogging(_ *fisk.ParseContext) error {
+	// help is shown before defaults are applied
+	if logLevel == "" {
+		return nil
+	}
+
+	var level slog.Level
+
+	err := level.UnmarshalText([]byte(logLevel))
+	if err != nil {
+		return err
+	}
+
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+	warnLegacyEnvars()
+
+	return nil
+}
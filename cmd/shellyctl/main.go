@@ -0,0 +1,756 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/dustin/go-humanize"
+	"github.com/ripienaar/shellyctl"
+)
+
+var (
+	addresses     []string
+	parallel      int
+	timeout       time.Duration
+	cacheTTL      time.Duration
+	waitForDevice time.Duration
+	socksProxy    string
+	userAgent     string
+	dryRun        bool
+	user          string
+	pass          string
+	jsonFormat    bool
+	choriaFormat  bool
+	labels        map[string]string
+
+	// displayLocation is the time zone device times are shown in
+	displayLocation = time.Local
+
+	// ctx is cancelled when the process is interrupted or terminated, stopping requests in progress
+	ctx context.Context
+	// stopCommand cancels ctx, commands in watch mode return and exit normally
+	stopCommand context.CancelFunc
+)
+
+func main() {
+	var stop context.CancelFunc
+	ctx, stop = signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ctx, stopCommand = context.WithCancel(ctx)
+	defer stopCommand()
+
+	// a second interrupt stops the process immediately
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	help := `Controls Shell Plug / Plug S Smart Plugs
+
+All flags can be set using environment variables named SHELLYCTL_<FLAG>, the
+variable for each flag is shown next to it in the help output.
+
+` + exitCodesHelp
+
+	app := fisk.New("shellyctl", help)
+	app.Version(version)
+	app.VersionFlag.NoEnvar()
+	app.DefaultEnvars()
+	app.HelpFlag.NoEnvar()
+	app.Terminate(terminate)
+	app.ErrorWriter(errorOutput)
+
+	labels = make(map[string]string)
+
+	app.Flag("address", "Device IP address or hostname, can be passed multiple times").Short('A').StringsVar(&addresses)
+	app.Flag("address-file", "File listing device addresses, one per line").PlaceHolder("FILE").ExistingFileVar(&addressFile)
+	app.Flag("username", "Device username").Short('U').StringVar(&user)
+	app.Flag("password", "Device password").Short('P').StringVar(&pass)
+	app.Flag("credential-file", "JSON or YAML file holding the username and password").PlaceHolder("FILE").StringVar(&credentialFile)
+	app.Flag("password-stdin", "Reads the device password from stdin").UnNegatableBoolVar(&passwordStdin)
+	app.Flag("cache-ttl", "How long device information is cached, 0 disables caching").Default("300s").DurationVar(&cacheTTL)
+	app.Flag("timeout", "Timeout for requests to the device").Default("10s").DurationVar(&timeout)
+	app.Flag("https", "Connect to the device using HTTPS").UnNegatableBoolVar(&useHTTPS)
+	app.Flag("insecure-skip-hostname-verify", "Verifies the device certificate but not that it matches the address").UnNegatableBoolVar(&skipHostnameCheck)
+	app.Flag("verify-ssl", "Verifies the device certificate against the system roots, disable using --no-verify-ssl").Default("true").BoolVar(&verifySSL)
+	app.Flag("ssl-min-version", "Minimum TLS version to accept from the device").Default("TLS1.2").EnumVar(&sslMinVersion, "TLS1.2", "TLS1.3")
+	app.Flag("ca-cert", "PEM encoded CA certificate to trust in addition to the system roots").PlaceHolder("FILE").ExistingFileVar(&caCert)
+	app.Flag("pin-fingerprint", "Trusts the device certificate with this fingerprint regardless of who issued it").PlaceHolder("SHA256:HEX").StringVar(&pinnedFingerprint)
+	app.Flag("trust-on-first-use", "Records the fingerprint of untrusted device certificates on first use").UnNegatableBoolVar(&trustOnFirstUse)
+	app.Flag("socks5-proxy", "SOCKS5 proxy to connect to the device through").PlaceHolder("ADDRESS").StringVar(&socksProxy)
+	app.Flag("user-agent", "User-Agent header sent to the device").Default("shellyctl/" + version).StringVar(&userAgent)
+	app.Flag("wait-for-device", "Waits up to this long for the device to become reachable").PlaceHolder("DURATION").DurationVar(&waitForDevice)
+	app.Flag("color", "Colorize output (auto, always, never)").Default("auto").EnumVar(&colorMode, "auto", "always", "never")
+	app.Flag("no-color", "Disables colorized output").UnNegatableBoolVar(&noColor)
+	app.Flag("log-level", "Minimum level of log messages to show (debug, info, warn, error)").Default("warn").EnumVar(&logLevel, "debug", "info", "warn", "error")
+	app.Flag("machine-exit-code", "Use exit codes that describe the failure").UnNegatableBoolVar(&machineExitCode)
+	app.Flag("success-url", "URL to POST to when the command succeeds, like a healthchecks.io ping URL").PlaceHolder("URL").StringVar(&successURL)
+	app.Flag("failure-url", "URL to POST the error message to when the command fails").PlaceHolder("URL").StringVar(&failureURL)
+	app.Flag("on-error", "Shell command to run when the command fails, {} expands to the error message").PlaceHolder("COMMAND").StringVar(&onErrorCommand)
+	app.Flag("output-file", "Writes command output to a file instead of stdout").PlaceHolder("FILE").StringVar(&outputFile)
+	app.Flag("output-append", "Appends to the --output-file rather than replacing it").UnNegatableBoolVar(&outputAppend)
+	app.Flag("write-pid-file", "Writes the process ID to a file that is removed on exit").PlaceHolder("FILE").StringVar(&pidFile)
+	app.Flag("parallel", "Number of devices to communicate with concurrently").Default("1").IntVar(&parallel)
+	app.Flag("circuit-breaker-threshold", "Skips devices that could not be reached this many times in a row").PlaceHolder("N").IntVar(&circuitBreakerThreshold)
+	app.Flag("rate-limit", "Maximum number of requests per second to send to all devices combined").PlaceHolder("N").Float64Var(&rateLimit)
+	app.Flag("simulate", "Runs commands against a simulated device instead of the network").UnNegatableBoolVar(&simulate)
+
+	app.PreAction(configureLogging)
+	app.PreAction(configurePidFile)
+	app.PreAction(configureAddresses)
+	app.PreAction(configureSimulator)
+	app.PreAction(configureCredentials)
+	app.PreAction(configureCACert)
+	app.PreAction(configureRateLimit)
+	app.PreAction(configureOutput)
+	app.PreAction(configureColor)
+
+	mqttControlFlags(dryRunFlag(app.Command("on", "Turns the device on").Action(onAction)))
+	mqttControlFlags(dryRunFlag(app.Command("off", "Turns the device off").Action(offAction)))
+	mqttControlFlags(dryRunFlag(app.Command("toggle", "Turns the device on when it is off and off when it is on").Action(toggleAction)))
+
+	info := app.Command("info", "Shows device information").Action(infoAction)
+	jsonFlags(info)
+	info.Flag("watch", "Continuously refresh the device information").UnNegatableBoolVar(&watchMode)
+	info.Flag("interval", "Interval between refreshes in watch mode").Default("5s").DurationVar(&watchInterval)
+	info.Flag("timestamps", "Adds the time to every line or a timestamp field to JSON output in watch mode").UnNegatableBoolVar(&watchTimestamps)
+	info.Flag("format", "Output format, tables are only used when writing to a terminal").Default("text").EnumVar(&outputFormat, "text", "table")
+	info.Flag("output-jq", "Filters JSON output using a jq expression").PlaceHolder("EXPR").StringVar(&outputJQ)
+	info.Flag("json-path", "Shows a single value from the JSON output, like .meters[0].power").PlaceHolder("EXPR").StringVar(&outputJSONPath)
+	info.Flag("output-template", "Renders output using a Go template, prefix with @ to read from a file").PlaceHolder("TEMPLATE").StringVar(&outputTemplate)
+
+	energy := app.Command("energy", "Retrieves device energy usage statistics").Action(energyAction)
+	jsonFlags(energy)
+	energy.Flag("choria", "Produce Choria Metric output").UnNegatableBoolVar(&choriaFormat)
+	energy.Flag("format", "Output format, tables are only used when writing to a terminal").Default("text").EnumVar(&outputFormat, "text", "table")
+	energy.Flag("watch", "Continuously refresh the energy usage").UnNegatableBoolVar(&watchMode)
+	energy.Flag("interval", "Interval between refreshes in watch mode").Default("5s").DurationVar(&watchInterval)
+	energy.Flag("timestamps", "Adds the time to every line or a timestamp field to JSON output in watch mode").UnNegatableBoolVar(&watchTimestamps)
+	energy.Flag("output-jq", "Filters JSON output using a jq expression").PlaceHolder("EXPR").StringVar(&outputJQ)
+	energy.Flag("json-path", "Shows a single value from the JSON output, like .meters[0].power").PlaceHolder("EXPR").StringVar(&outputJSONPath)
+	energy.Flag("output-template", "Renders output using a Go template, prefix with @ to read from a file").PlaceHolder("TEMPLATE").StringVar(&outputTemplate)
+	energy.Flag("otel-endpoint", "Exports metrics to an OpenTelemetry collector using OTLP over HTTP").PlaceHolder("URL").StringVar(&otelEndpoint)
+	energy.Flag("graphite-address", "Sends metrics to a Graphite server using the plaintext protocol").PlaceHolder("HOST:PORT").StringVar(&graphiteAddress)
+	energy.Flag("graphite-prefix", "Prefix for metric paths sent to Graphite").Default("shelly").StringVar(&graphitePrefix)
+	energy.Flag("serve-grafana-datasource", "Serves a Grafana SimpleJSON datasource on this address").PlaceHolder("LISTEN").StringVar(&grafanaListen)
+	energy.Flag("tee-json", "Appends every reading to a file as JSON lines in addition to the normal output").PlaceHolder("PATH").StringVar(&teeJSON)
+	energy.Flag("tee-prometheus", "Maintains a Prometheus textfile collector file with the latest readings").PlaceHolder("PATH").StringVar(&teePrometheus)
+	energy.Flag("tee-influx", "Appends every reading to a file using the InfluxDB line protocol").PlaceHolder("PATH").StringVar(&teeInflux)
+	energy.Flag("unix-socket", "Serves the latest readings on a Unix socket in watch mode").PlaceHolder("PATH").StringVar(&unixSocket)
+	energy.Flag("label", "Labels to apply to Choria, OpenTelemetry, Graphite and --tee metric outputs").Envar("SHELLYCTL_LABELS").SetValue((*labelsValue)(&labels))
+	energy.Flag("device-label", "Adds the device MAC address and IP address as device and ip labels").UnNegatableBoolVar(&deviceLabels)
+
+	timer := app.Command("timer", "Manages Gen 1 relay timers")
+	timer.Command("status", "Shows the state of the relay timer").Default().Action(timerStatusAction)
+	dryRunFlag(timer.Command("cancel", "Cancels the relay timer and turns the device off").Action(timerCancelAction))
+
+	scheduleOnce := dryRunFlag(app.Command("schedule-once", "Turns the device on or off once at a future time").Action(scheduleOnceAction))
+	scheduleOnce.Flag("in", "How long from now to perform the action").Required().PlaceHolder("DURATION").DurationVar(&scheduleIn)
+	scheduleOnce.Flag("action", "State to switch the device to").Required().EnumVar(&scheduleAction, "on", "off")
+
+	overpower := app.Command("overpower", "Manages Gen 1 overpower protection")
+	overpower.Command("get", "Shows the overpower protection threshold").Default().Action(overpowerGetAction)
+	overpowerSet := dryRunFlag(overpower.Command("set", "Sets the overpower protection threshold").Action(overpowerSetAction))
+	overpowerSet.Flag("watts", "Power in Watt that triggers overpower protection").Required().IntVar(&overpowerWatts)
+	dryRunFlag(overpower.Command("reset", "Clears a triggered overpower state by turning the device on").Action(overpowerResetAction))
+
+	actions := app.Command("actions", "Manages Gen 1 URL actions called on device events")
+	actions.Command("list", "Shows the configured URL actions").Default().Action(actionsListAction)
+	actionsSet := dryRunFlag(actions.Command("set", "Sets the URL called when an event occurs").Action(actionsSetAction))
+	actionsSet.Flag("event", "Event to configure, like relay_on or relay_off").Required().HintOptions(sortedKeys(gen1ActionEvents)...).StringVar(&actionEvent)
+	actionsSet.Flag("url", "URL to call when the event occurs").Required().StringVar(&actionURL)
+	actionsDelete := dryRunFlag(actions.Command("delete", "Removes the URL called when an event occurs").Action(actionsDeleteAction))
+	actionsDelete.Flag("event", "Event to remove the URL action for").Required().HintOptions(sortedKeys(gen1ActionEvents)...).StringVar(&actionEvent)
+
+	brightness := app.Command("brightness", "Manages the brightness of Gen 1 Shelly Dimmers")
+	brightness.Command("get", "Shows the light brightness").Default().Action(brightnessGetAction)
+	brightnessSet := dryRunFlag(brightness.Command("set", "Sets the light brightness").Action(brightnessSetAction))
+	brightnessSet.Flag("value", "Brightness in percent").Required().IntVar(&dimmerBrightness)
+
+	temperature := app.Command("temperature", "Shows the temperature and humidity of Shelly H&T sensors and TRVs").Action(temperatureAction)
+	jsonFlags(temperature)
+
+	dryRunFlag(app.Command("open", "Opens a roller shutter or cover").Action(rollerOpenAction))
+	dryRunFlag(app.Command("close", "Closes a roller shutter or cover").Action(rollerCloseAction))
+	dryRunFlag(app.Command("stop", "Stops a moving roller shutter or cover").Action(rollerStopAction))
+	position := dryRunFlag(app.Command("position", "Moves a calibrated roller shutter or cover to a position").Action(rollerPositionAction))
+	position.Flag("value", "Position in percent, 100 is fully open").Required().IntVar(&rollerPosition)
+
+	ecoMode := app.Command("eco-mode", "Manages Gen 2 eco mode")
+	ecoMode.Command("get", "Shows if eco mode is enabled").Default().Action(ecoModeGetAction)
+	dryRunFlag(ecoMode.Command("enable", "Enables eco mode").Action(ecoModeEnableAction))
+	dryRunFlag(ecoMode.Command("disable", "Disables eco mode").Action(ecoModeDisableAction))
+
+	led := app.Command("led", "Manages Gen 2 LED behavior")
+	led.Command("show", "Shows the LED configuration").Default().Action(ledShowAction)
+	ledSet := dryRunFlag(led.Command("set", "Sets the LED mode").Action(ledSetAction))
+	ledSet.Flag("mode", "LED mode to set").Required().EnumVar(&ledMode, "off", "switch", "power", "night")
+	ledBrightnessCmd := dryRunFlag(led.Command("brightness", "Sets the LED brightness").Action(ledBrightnessAction))
+	ledBrightnessCmd.Flag("value", "Brightness in percent").Required().IntVar(&ledBrightness)
+
+	input := app.Command("input", "Manages Gen 2 device inputs")
+	input.Flag("id", "Input instance").Default("0").IntVar(&inputID)
+	input.Command("status", "Shows the state of the input").Default().Action(inputStatusAction)
+	inputConfig := input.Command("config", "Manages the input configuration")
+	inputConfig.Command("get", "Shows the input configuration").Default().Action(inputConfigGetAction)
+	inputConfigSet := dryRunFlag(inputConfig.Command("set", "Updates the input configuration").Action(inputConfigSetAction))
+	inputConfigSet.Flag("type", "Input type, detached and activated configure how the input controls the switch").Required().EnumVar(&inputType, "switch", "button", "detached", "activated")
+
+	circuitBreaker := app.Command("circuit-breaker", "Manages devices skipped by --circuit-breaker-threshold")
+	circuitBreaker.Command("reset", "Clears the failure count of the devices").Action(circuitBreakerResetAction)
+
+	app.Command("man", "Generates a man page").Hidden().Action(manAction(app))
+
+	versionCmd := app.Command("version", "Shows the version and build information").Action(versionAction)
+	jsonFlags(versionCmd)
+
+	app.Command("pin-fingerprint", "Trusts the current device certificate for --https").Action(pinFingerprintAction)
+
+	health := app.Command("health", "Checks the device state against thresholds, fails when any check fails").Action(healthAction)
+	health.Flag("expected-state", "State the relay is expected to be in, on or off").PlaceHolder("STATE").EnumVar(&healthExpectedState, "on", "off")
+	health.Flag("expected-power-min", "Minimum expected power in Watt").PlaceHolder("W").Float64Var(&healthPowerMin)
+	health.Flag("expected-power-max", "Maximum expected power in Watt").PlaceHolder("W").Float64Var(&healthPowerMax)
+	health.Flag("max-temp-celsius", "Maximum safe device temperature").PlaceHolder("C").Float64Var(&healthMaxTemp)
+	health.Flag("min-wifi-rssi", "Minimum WiFi signal strength, like --min-wifi-rssi=-75").PlaceHolder("DBM").IntVar(&healthMinRSSI)
+
+	ping := app.Command("ping", "Checks if the device is reachable").Action(pingAction)
+	ping.Flag("count", "Number of times to contact the device").Short('c').Default("1").IntVar(&pingCount)
+	ping.Flag("interval", "Time to wait between attempts").Default("1s").DurationVar(&pingInterval)
+
+	notify := app.Command("notify", "Runs a command or posts to a URL when the relay turns on or off").Action(notifyAction)
+	notify.Flag("interval", "Interval between polling the device").Default("5s").DurationVar(&notifyInterval)
+	notify.Flag("on-turn-on", "Shell command to run when the relay turns on").PlaceHolder("COMMAND").StringVar(&notifyOnCommand)
+	notify.Flag("on-turn-off", "Shell command to run when the relay turns off").PlaceHolder("COMMAND").StringVar(&notifyOffCommand)
+	notify.Flag("on-turn-on-url", "URL to POST a JSON notification to when the relay turns on").PlaceHolder("URL").StringVar(&notifyOnURL)
+	notify.Flag("on-turn-off-url", "URL to POST a JSON notification to when the relay turns off").PlaceHolder("URL").StringVar(&notifyOffURL)
+
+	exporter := app.Command("exporter", "Serves device metrics for Prometheus").Action(exporterAction)
+	exporter.Flag("listen", "Address to serve /metrics on").Default(":9100").StringVar(&exporterListen)
+	exporter.Flag("scrape-interval", "Interval between reading the devices").Default("15s").DurationVar(&exporterInterval)
+	exporter.Flag("label", "Labels to add to every metric").Envar("SHELLYCTL_LABELS").SetValue((*labelsValue)(&labels))
+	exporter.Flag("device-label", "Adds the device MAC address and IP address as device and ip labels").UnNegatableBoolVar(&deviceLabels)
+
+	monitor := app.Command("monitor", "Shows relay and power updates published by the devices to MQTT").Action(monitorAction)
+	mqttBrokerFlags(monitor)
+	monitor.Flag("label", "Labels to prefix output lines with").Envar("SHELLYCTL_LABELS").SetValue((*labelsValue)(&labels))
+
+	export := app.Command("export", "Saves the device information, status and configuration as JSON").Action(exportAction)
+	export.Flag("output", "File to write the document to").StringVar(&exportFile)
+
+	importCmd := dryRunFlag(app.Command("import", "Applies the configuration from a document saved using export").Action(importAction))
+	importCmd.Flag("file", "File to read the document from").Required().ExistingFileVar(&importFile)
+
+	diff := app.Command("diff", "Compares the configuration of two devices").Action(diffAction)
+	diff.Flag("from", "Device to compare").Required().StringVar(&diffFrom)
+	diff.Flag("to", "Device to compare with").Required().StringVar(&diffTo)
+
+	copySettingsCmd := dryRunFlag(app.Command("copy-settings", "Copies the configuration of one device to another").Action(copySettingsAction))
+	copySettingsCmd.Flag("from", "Device to copy the configuration from").Required().StringVar(&copyFrom)
+	copySettingsCmd.Flag("to", "Device to apply the configuration to").Required().StringVar(&copyTo)
+	copySettingsCmd.Flag("exclude", "Settings or components to skip, like wifi_ap or switch:0.name").PlaceHolder("FIELD,...").StringsVar(&copyExclude)
+
+	backup := app.Command("backup", "Saves the device configuration").Action(backupAction)
+	backup.Flag("output", "File to write the configuration to").StringVar(&backupFile)
+
+	restore := dryRunFlag(app.Command("restore", "Restores the device configuration from a backup").Action(restoreAction))
+	restore.Flag("input", "File to read the configuration from").Required().ExistingFileVar(&restoreFile)
+
+	settings := app.Command("settings", "Manages Gen 1 device settings")
+	settingsGet := settings.Command("get", "Shows device settings").Action(settingsGetAction)
+	settingsGet.Arg("key", "Setting to show").StringVar(&settingKey)
+	jsonFlags(settingsGet)
+
+	settingsSet := dryRunFlag(settings.Command("set", "Updates a device setting").HelpLong(gen1SettingsHelp()).Action(settingsSetAction))
+	settingsSet.Arg("key", "Setting to update").Required().HintOptions(gen1SettingNames()...).StringVar(&settingKey)
+	settingsSet.Arg("value", "Value to set").Required().StringVar(&settingValue)
+
+	config := app.Command("config", "Manages Gen 2 device component configuration")
+	configGet := config.Command("get", "Shows the configuration of a component").Action(configGetAction)
+	configGet.Flag("component", "Component to show").Required().EnumVar(&configComponent, gen2ComponentNames()...)
+	configGet.Flag("id", "Component instance").Default("0").IntVar(&configID)
+	configGet.Flag("json-pretty", "Produce pretty printed JSON output, the default").UnNegatableBoolVar(&jsonFormat)
+	configGet.Flag("json-compact", "Produce JSON output on a single line").UnNegatableBoolVar(&jsonCompact)
+
+	configSet := dryRunFlag(config.Command("set", "Updates the configuration of a component").Action(configSetAction))
+	configSet.Flag("component", "Component to update").Required().EnumVar(&configComponent, gen2ComponentNames()...)
+	configSet.Flag("id", "Component instance").Default("0").IntVar(&configID)
+	configSet.Flag("params", "Configuration to set as JSON").Required().StringVar(&configParams)
+
+	network := app.Command("network", "Manages Gen 2 device WiFi configuration")
+	network.Command("show", "Shows the WiFi configuration and status").Default().Action(networkShowAction)
+
+	networkSetAP := dryRunFlag(network.Command("set-ap", "Configures the WiFi network the device connects to").Action(networkSetAPAction))
+	networkSetAP.Flag("ssid", "Network to connect to").Required().StringVar(&networkSSID)
+	networkSetAP.Flag("pass", "Password for the network").StringVar(&networkPass)
+	networkSetAP.Flag("reboot", "Reboots the device after updating the configuration").UnNegatableBoolVar(&networkReboot)
+
+	networkEnableAP := dryRunFlag(network.Command("enable-ap", "Enables the device access point").Action(networkEnableAPAction))
+	networkEnableAP.Flag("reboot", "Reboots the device after updating the configuration").UnNegatableBoolVar(&networkReboot)
+
+	networkDisableAP := dryRunFlag(network.Command("disable-ap", "Disables the device access point").Action(networkDisableAPAction))
+	networkDisableAP.Flag("reboot", "Reboots the device after updating the configuration").UnNegatableBoolVar(&networkReboot)
+
+	provision := dryRunFlag(app.Command("provision", "Connects a new device in access point mode to a WiFi network").Action(provisionAction))
+	provision.Flag("ap-ssid", "Access point of the new device to join using NetworkManager").StringVar(&provisionAPSSID)
+	provision.Flag("ap-address", "Address of the device on its access point").Default("192.168.33.1").StringVar(&provisionAPAddress)
+	provision.Flag("wifi-ssid", "Network the device should connect to").Required().StringVar(&provisionSSID)
+	provision.Flag("wifi-pass", "Password for the network").StringVar(&provisionPass)
+	provision.Flag("wait", "Waits up to this long for the device to join the network").PlaceHolder("DURATION").DurationVar(&provisionWait)
+
+	applyLegacyEnvars()
+	app.MustParseWithUsage(os.Args[1:])
+
+	completed()
+}
+
+// jsonFlags adds the --json, --json-pretty and --json-compact flags to commands that can produce JSON output
+func jsonFlags(cmd *fisk.CmdClause) *fisk.CmdClause {
+	cmd.Flag("json", "Produce JSON output").UnNegatableBoolVar(&jsonFormat)
+	cmd.Flag("json-pretty", "Produce pretty printed JSON output").UnNegatableBoolVar(&jsonFormat)
+	cmd.Flag("json-compact", "Produce JSON output on a single line").UnNegatableBoolVar(&jsonCompact)
+	return cmd
+}
+
+// dryRunFlag adds the --dry-run flag to commands that change the device
+func dryRunFlag(cmd *fisk.CmdClause) *fisk.CmdClause {
+	cmd.Flag("dry-run", "Shows the requests that would change the device without sending them").UnNegatableBoolVar(&dryRun)
+	return cmd
+}
+
+func deviceUrl(address string) url.URL {
+	if simulator != nil {
+		return url.URL{Scheme: "http", Host: simulator.Listener.Addr().String()}
+	}
+
+	scheme := "http"
+	if useHTTPS {
+		scheme = "https"
+	}
+
+	return url.URL{
+		Scheme: scheme,
+		Host:   deviceHost(address),
+	}
+}
+
+func energyAction(_ *fisk.ParseContext) error {
+	update := func() error {
+		return forEachDevice(timestamped(energyDevice))
+	}
+
+	if grafanaListen != "" {
+		return serveGrafana(grafanaListen)
+	}
+
+	if otelEndpoint != "" {
+		var err error
+		otelMetrics, err = newOtelExporter(ctx, otelEndpoint)
+		if err != nil {
+			return err
+		}
+		// ctx is cancelled when interrupted, the last readings are still sent
+		defer func() {
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			otelMetrics.shutdown(shutdownCtx)
+		}()
+
+		update = func() error {
+			err := forEachDevice(timestamped(energyDevice))
+
+			flushCtx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			return errors.Join(err, otelMetrics.flush(flushCtx))
+		}
+	}
+
+	var err error
+	tees, err = openTeeSinks()
+	if err != nil {
+		return err
+	}
+	if tees != nil {
+		atExit(tees.close)
+	}
+
+	if unixSocket != "" {
+		if !watchMode {
+			return fmt.Errorf("--unix-socket requires --watch")
+		}
+
+		err := serveUnixSocket(unixSocket)
+		if err != nil {
+			return err
+		}
+	}
+
+	if watchMode {
+		return watchLoop(watchInterval, update)
+	}
+
+	return update()
+}
+
+func energyDevice(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+	status, err := plug.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	readingLabels := metricLabels(ctx, address, plug)
+
+	// battery powered sensors have no meters, only their battery level is reported
+	if len(status.Meters) == 0 && status.Bat != nil {
+		return energyBattery(address, status.Bat, readingLabels, w)
+	}
+
+	if len(status.Meters) != 1 {
+		return fmt.Errorf("no meter information received")
+	}
+	if len(status.Relays) != len(status.Meters) {
+		return fmt.Errorf("invalid relay information received")
+	}
+
+	m := status.Meters[0]
+	r := status.Relays[0]
+
+	isOn := float64(0)
+	if r.IsOn {
+		isOn = 1
+	}
+
+	reading := map[string]any{
+		"power_watt":      m.Power,
+		"power_total_kwh": m.TotalKWh(),
+		"is_on":           isOn,
+	}
+
+	if otelMetrics != nil {
+		otelMetrics.record(ctx, address, readingLabels, m.Power, m.TotalKWh(), isOn)
+	}
+
+	if unixSocket != "" {
+		latestReadings.set(address, reading)
+	}
+
+	if tees != nil {
+		err = tees.write(address, readingLabels, reading, time.Now())
+		if err != nil {
+			return err
+		}
+	}
+
+	if graphiteAddress != "" {
+		err = sendGraphite(ctx, address, readingLabels, map[string]float64{
+			"power_watt":      m.Power,
+			"power_total_kwh": m.TotalKWh(),
+			"relay_on":        isOn,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case outputTemplate != "":
+		nfo, err := plug.Info(ctx)
+		if err != nil {
+			return err
+		}
+
+		return renderOutputTemplate(w, address, nfo, status)
+
+	case jsonFormat:
+		return writeJSON(w, reading)
+
+	case choriaFormat:
+		data := map[string]any{
+			"labels": readingLabels,
+			"metrics": map[string]any{
+				"current_power_watt": reading["power_watt"],
+				"today_energy_kwh":   reading["power_total_kwh"],
+				"relay_on":           reading["is_on"],
+			}}
+
+		return writeJSON(w, data)
+
+	case tableFormat():
+		rows := energyReadings.add(address, []string{
+			time.Now().Format(time.TimeOnly),
+			stateBool(r.IsOn),
+			fmt.Sprintf("%.2f", m.Power),
+			fmt.Sprintf("%.2f", m.TotalKWh()),
+		})
+
+		fmt.Fprintf(w, "Meter Information for %s\n", address)
+		fmt.Fprintln(w)
+		renderTable(w, []string{"Time", "Powered On", "Power (Watt)", "Total Consumption (kWh)"}, rows)
+
+	default:
+		fmt.Fprintln(w, "Meter Information")
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "          Powered On: %s\n", stateBool(r.IsOn))
+		fmt.Fprintf(w, "               Power: %.2f Watt\n", m.Power)
+		fmt.Fprintf(w, "   Total Consumption: %.2f kWh\n", m.TotalKWh())
+	}
+
+	return nil
+}
+
+// energyBattery shows the battery level of a battery powered device
+func energyBattery(address string, bat *shellyctl.Battery, readingLabels map[string]string, w io.Writer) error {
+	switch {
+	case jsonFormat:
+		return writeJSON(w, map[string]any{"battery_percent": bat.Value})
+
+	case choriaFormat:
+		return writeJSON(w, map[string]any{
+			"labels":  readingLabels,
+			"metrics": map[string]any{"battery_percent": bat.Value},
+		})
+	}
+
+	fmt.Fprintf(w, "Battery Information for %s\n", address)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "       Battery Level: %d %%\n", bat.Value)
+	fmt.Fprintf(w, "     Battery Voltage: %.2f V\n", bat.Voltage)
+
+	return nil
+}
+
+func infoAction(_ *fisk.ParseContext) error {
+	if watchMode {
+		return watchLoop(watchInterval, func() error {
+			return forEachDevice(timestamped(func(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+				return infoDevice(ctx, address, plug, &atomic.Bool{}, w)
+			}))
+		})
+	}
+
+	var updates atomic.Bool
+	err := forEachDevice(func(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+		return infoDevice(ctx, address, plug, &updates, w)
+	})
+	if err != nil {
+		return err
+	}
+
+	if machineExitCode && updates.Load() {
+		terminate(exitUpdateAvailable)
+	}
+
+	return nil
+}
+
+// infoSection is a titled group of attributes shown by info
+type infoSection struct {
+	title string
+	rows  [][2]string
+}
+
+func (s *infoSection) add(label string, value string) {
+	s.rows = append(s.rows, [2]string{label, value})
+}
+
+func infoDevice(ctx context.Context, address string, plug shellyctl.Plug, updates *atomic.Bool, w io.Writer) error {
+	nfo, err := plug.Info(ctx)
+	if err != nil {
+		return err
+	}
+	status, err := plug.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	if status.Update.HasUpdate {
+		updates.Store(true)
+	}
+
+	switch {
+	case outputTemplate != "":
+		return renderOutputTemplate(w, address, nfo, status)
+
+	case jsonFormat:
+		return writeJSON(w, templateData{Address: address, Info: nfo, Status: status})
+	}
+
+	sections := []infoSection{
+		{"Device Information", [][2]string{
+			{"Device Type", nfo.Type},
+			{"Firmware", nfo.FW},
+			{"MAC Address", status.MAC},
+		}},
+	}
+
+	deviceStatus := infoSection{"Device Status", [][2]string{
+		{"Time", time.Unix(status.Unixtime, 0).In(displayLocation).String()},
+		{"Uptime", (time.Duration(status.Uptime) * time.Second).String()},
+		{"Memory Used", humanize.IBytes(uint64(status.RamTotal))},
+		{"Memory Free", humanize.IBytes(uint64(status.RamFree))},
+		{"Storage Total", humanize.IBytes(uint64(status.FsSize))},
+		{"Storage Free", humanize.IBytes(uint64(status.FsFree))},
+		{"Temperature", fmt.Sprintf("%.1f °C", status.Temperature)},
+	}}
+	if status.OverTemperature {
+		deviceStatus.add("Over Temperature", warnBool(status.OverTemperature))
+	}
+	sections = append(sections, deviceStatus)
+
+	network := infoSection{"Network Information", [][2]string{
+		{"IP Address", status.WiFi.IP},
+		{"WiFi SSID", status.WiFi.SSID},
+		{"WiFi Strength", fmt.Sprint(status.WiFi.RSSI)},
+		{"Cloud Enabled", fmt.Sprint(status.Cloud.Enabled)},
+	}}
+	if status.Cloud.Enabled {
+		network.add("Cloud Connected", fmt.Sprint(status.Cloud.Connected))
+	}
+	network.add("MQTT Connected", fmt.Sprint(status.MQTT.Connected))
+	sections = append(sections, network)
+
+	sections = append(sections, infoSection{"Updates Information", [][2]string{
+		{"Has Update", warnBool(status.Update.HasUpdate)},
+		{"Latest Available", status.Update.NewVersion},
+	}})
+
+	if len(status.Relays) == 1 {
+		relay := status.Relays[0]
+		section := infoSection{"Relay Information", [][2]string{
+			{"Power Status", onOffString(relay.IsOn)},
+			{"Overpower", warnBool(relay.Overpower)},
+			{"Timer", fmt.Sprint(relay.HasTimer)},
+		}}
+		if relay.HasTimer {
+			section.add("Started", time.Unix(relay.TimerStarted, 0).In(displayLocation).String())
+			section.add("Duration", (time.Duration(relay.TimerDuration) * time.Second).String())
+			section.add("Remaining", (time.Duration(relay.TimerRemaining) * time.Second).String())
+		}
+		sections = append(sections, section)
+	}
+
+	if len(status.Meters) == 1 {
+		sections = append(sections, infoSection{"Meter Information", [][2]string{
+			{"Power", fmt.Sprintf("%.2f Watt", status.Meters[0].Power)},
+			{"Total Consumption", fmt.Sprintf("%.2f kWh", status.Meters[0].TotalKWh())},
+		}})
+	}
+
+	if len(status.Lights) == 1 {
+		light := status.Lights[0]
+		sections = append(sections, infoSection{"Light Information", [][2]string{
+			{"Power Status", onOffString(light.IsOn)},
+			{"Brightness", fmt.Sprintf("%d%%", light.Brightness)},
+			{"Transition", (time.Duration(light.Transition) * time.Millisecond).String()},
+		}})
+	}
+
+	if roller, ok := plug.(shellyctl.Roller); ok {
+		state, err := roller.RollerStatus(ctx)
+		if err != nil {
+			return err
+		}
+
+		sections = append(sections, infoSection{"Roller Information", [][2]string{
+			{"Position", fmt.Sprintf("%d%%", state.CurrentPos)},
+			{"Moving", fmt.Sprint(state.Moving())},
+			{"Last Direction", state.LastDirection},
+		}})
+	}
+
+	fmt.Fprintf(w, "Shelly device information for %s\n", address)
+
+	if tableFormat() {
+		var rows [][]string
+		for _, section := range sections {
+			for _, row := range section.rows {
+				rows = append(rows, []string{row[0], row[1]})
+			}
+		}
+
+		fmt.Fprintln(w)
+		renderTable(w, []string{"Attribute", "Value"}, rows)
+
+		return nil
+	}
+
+	for _, section := range sections {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, section.title)
+		fmt.Fprintln(w)
+		for _, row := range section.rows {
+			fmt.Fprintf(w, "%20s: %s\n", row[0], row[1])
+		}
+	}
+
+	return nil
+}
+
+func onAction(_ *fisk.ParseContext) error {
+	if mqttControl {
+		return mqttSwitch("on")
+	}
+
+	return forEachDevice(onDevice)
+}
+
+func onDevice(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+	_, err := plug.TurnOn(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Device %s turned on\n", address)
+
+	return nil
+}
+
+func offAction(_ *fisk.ParseContext) error {
+	if mqttControl {
+		return mqttSwitch("off")
+	}
+
+	return forEachDevice(offDevice)
+}
+
+func offDevice(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+	_, err := plug.TurnOff(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Device %s turned off\n", address)
+
+	return nil
+}
+
+func toggleAction(_ *fisk.ParseContext) error {
+	if mqttControl {
+		return mqttSwitch("toggle")
+	}
+
+	return forEachDevice(toggleDevice)
+}
+
+func toggleDevice(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+	relay, err := plug.RelayStatus(ctx)
+	if err != nil {
+		return err
+	}
+
+	if relay.IsOn {
+		return offDevice(ctx, address, plug, w)
+	}
+
+	return onDevice(ctx, address, plug, w)
+}
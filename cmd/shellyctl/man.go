@@ -0,0 +1,24 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/choria-io/fisk"
+)
+
+// manAction renders a man page for app to stdout
+func manAction(app *fisk.Application) fisk.Action {
+	return func(c *fisk.ParseContext) error {
+		// include the environment variable for every flag and keep the formatting of the description
+		tmpl := strings.Replace(fisk.ManPageTemplate, "{{.Help}}", "{{.HelpWithEnvar}}", 1)
+		tmpl = strings.Replace(tmpl, "{{.App.Help}}", ".nf\n{{.App.Help}}\n.fi", 1)
+
+		fmt.Fprintln(os.Stderr, "Install using: shellyctl man > /usr/local/share/man/man1/shellyctl.1")
+
+		app.UsageWriter(output)
+
+		return app.UsageForContextWithTemplate(c, 2, tmpl)
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/ripienaar/shellyctl"
+)
+
+// mqttDevice is a device monitored using MQTT
+type mqttDevice struct {
+	address    string
+	prefix     string // topic all messages of the device are published below
+	generation int
+}
+
+// mqttDeviceTopics determines the MQTT topic prefix of the device, Gen 1 devices publish below shellies/<id>
+// while Gen 2 devices publish below their configured topic prefix
+func mqttDeviceTopics(ctx context.Context, address string, plug shellyctl.Plug) (*mqttDevice, error) {
+	nfo, err := plug.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dev := &mqttDevice{address: address, generation: nfo.Generation()}
+
+	if dev.generation > 1 {
+		var cfg struct {
+			Enable      bool   `json:"enable"`
+			TopicPrefix string `json:"topic_prefix"`
+		}
+		err = plug.RPC(ctx, "MQTT.GetConfig", nil, &cfg)
+		if err != nil {
+			return nil, err
+		}
+		if !cfg.Enable {
+			return nil, fmt.Errorf("MQTT is not enabled on %s", address)
+		}
+
+		dev.prefix = cfg.TopicPrefix
+
+		return dev, nil
+	}
+
+	settings, err := plug.Settings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	mqtt, _ := settings["mqtt"].(map[string]any)
+	if enabled, _ := mqtt["enable"].(bool); !enabled {
+		return nil, fmt.Errorf("MQTT is not enabled on %s", address)
+	}
+
+	id, _ := mqtt["id"].(string)
+	if id == "" {
+		return nil, fmt.Errorf("could not determine the MQTT id of %s", address)
+	}
+
+	dev.prefix = "shellies/" + id
+
+	return dev, nil
+}
+
+func monitorAction(_ *fisk.ParseContext) error {
+	if mqttBroker == "" {
+		return fmt.Errorf("required flag --mqtt-broker not provided")
+	}
+
+	var devices []*mqttDevice
+	var mu sync.Mutex
+
+	err := forEachDevice(func(ctx context.Context, address string, plug shellyctl.Plug, _ io.Writer) error {
+		dev, err := mqttDeviceTopics(ctx, address, plug)
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		devices = append(devices, dev)
+		mu.Unlock()
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	client, err := dialMQTT(ctx, mqttBroker, mqttUser, mqttPass)
+	if err != nil {
+		return err
+	}
+	defer client.close()
+
+	var topics []string
+	for _, dev := range devices {
+		topics = append(topics, dev.prefix+"/#")
+	}
+
+	err = client.subscribe(topics...)
+	if err != nil {
+		return err
+	}
+
+	return client.receive(ctx, func(topic string, payload []byte) {
+		for _, dev := range devices {
+			sub, ok := strings.CutPrefix(topic, dev.prefix+"/")
+			if !ok {
+				continue
+			}
+
+			line := formatMQTTMessage(dev.generation, sub, payload)
+			if line == "" {
+				return
+			}
+
+			fmt.Fprintf(output, "%s%s %s: %s\n", labelsPrefix(), time.Now().Format(time.TimeOnly), dev.address, line)
+
+			return
+		}
+	})
+}
+
+// labelsPrefix formats --label as a prefix for output lines
+func labelsPrefix() string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", k, labels[k]))
+	}
+
+	return "[" + strings.Join(pairs, " ") + "] "
+}
+
+// formatMQTTMessage describes a message published to topic below the device prefix, messages that are not
+// of interest produce an empty string
+func formatMQTTMessage(generation int, topic string, payload []byte) string {
+	value := string(payload)
+
+	if topic == "online" {
+		if value == "true" {
+			return "Device is online"
+		}
+		return "Device is offline"
+	}
+
+	if generation > 1 {
+		component, ok := strings.CutPrefix(topic, "status/switch:")
+		if !ok {
+			return ""
+		}
+
+		var status struct {
+			Output *bool    `json:"output"`
+			APower *float64 `json:"apower"`
+		}
+		err := json.Unmarshal(payload, &status)
+		if err != nil {
+			return ""
+		}
+
+		var parts []string
+		if status.Output != nil {
+			parts = append(parts, "turned "+onOffString(*status.Output))
+		}
+		if status.APower != nil {
+			parts = append(parts, fmt.Sprintf("power %.2f Watt", *status.APower))
+		}
+		if len(parts) == 0 {
+			return ""
+		}
+
+		return fmt.Sprintf("Switch %s %s", component, strings.Join(parts, ", "))
+	}
+
+	parts := strings.Split(topic, "/")
+	if len(parts) < 2 || parts[0] != "relay" {
+		return ""
+	}
+
+	switch {
+	case len(parts) == 2:
+		return fmt.Sprintf("Relay %s turned %s", parts[1], onOffString(value == "on"))
+
+	case len(parts) == 3 && parts[2] == "power":
+		return fmt.Sprintf("Relay %s power %s Watt", parts[1], value)
+
+	case len(parts) == 3 && parts[2] == "energy":
+		// Gen 1 devices publish the energy in Watt-minutes like the meters in /status
+		total, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return ""
+		}
+		meter := shellyctl.Meter{Total: total}
+		return fmt.Sprintf("Relay %s total consumption %.2f kWh", parts[1], meter.TotalKWh())
+	}
+
+	return ""
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestFormatMQTTMessage(t *testing.T) {
+	resetOutputFlags(t)
+
+	cases := []struct {
+		generation int
+		topic      string
+		payload    string
+		expected   string
+	}{
+		{1, "relay/0", "on", "Relay 0 turned On"},
+		{1, "relay/0/power", "12.34", "Relay 0 power 12.34 Watt"},
+		{1, "relay/0/energy", "60000", "Relay 0 total consumption 1.00 kWh"},
+		{1, "online", "false", "Device is offline"},
+		{1, "announce", "{}", ""},
+		{2, "status/switch:0", `{"id":0,"output":false,"apower":0}`, "Switch 0 turned Off, power 0.00 Watt"},
+		{2, "status/sys", `{"uptime":10}`, ""},
+	}
+
+	for _, c := range cases {
+		line := formatMQTTMessage(c.generation, c.topic, []byte(c.payload))
+		if line != c.expected {
+			t.Errorf("expected %q for %s got %q", c.expected, c.topic, line)
+		}
+	}
+}
+
+func TestLabelsPrefix(t *testing.T) {
+	resetOutputFlags(t)
+
+	labels = map[string]string{}
+	if labelsPrefix() != "" {
+		t.Fatalf("expected no prefix without labels")
+	}
+
+	labels = map[string]string{"room": "office", "floor": "1"}
+	if prefix := labelsPrefix(); prefix != "[floor=1 room=office] " {
+		t.Fatalf("unexpected prefix %q", prefix)
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// MQTT 3.1.1 control packet types, only those needed to subscribe to and publish device topics are supported
+const (
+	mqttConnect    = 1
+	mqttConnAck    = 2
+	mqttPublish    = 3
+	mqttSubscribe  = 8
+	mqttSubAck     = 9
+	mqttPingReq    = 12
+	mqttDisconnect = 14
+
+	mqttKeepAlive = 30 * time.Second
+)
+
+var (
+	mqttBroker string
+	mqttUser   string
+	mqttPass   string
+)
+
+// mqttClient is a minimal MQTT 3.1.1 client using QoS 0
+type mqttClient struct {
+	conn   net.Conn
+	r      *bufio.Reader
+	mu     sync.Mutex
+	nextID uint16
+}
+
+// dialMQTT connects to broker, credentials are only sent when user is set
+func dialMQTT(ctx context.Context, broker string, user string, pass string) (*mqttClient, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", broker)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to MQTT broker: %v", err)
+	}
+
+	c := &mqttClient{conn: conn, r: bufio.NewReader(conn)}
+
+	flags := byte(0x02) // clean session
+	if user != "" {
+		flags |= 0x80 | 0x40
+	}
+
+	var body []byte
+	body = appendMQTTString(body, "MQTT")
+	body = append(body, 4, flags)
+	body = binary.BigEndian.AppendUint16(body, uint16(mqttKeepAlive/time.Second))
+	body = appendMQTTString(body, fmt.Sprintf("shellyctl-%d", os.Getpid()))
+	if user != "" {
+		body = appendMQTTString(body, user)
+		body = appendMQTTString(body, pass)
+	}
+
+	if timeout > 0 {
+		conn.SetDeadline(time.Now().Add(timeout))
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	err = c.write(mqttConnect<<4, body)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	kind, resp, err := c.read()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if kind != mqttConnAck || len(resp) != 2 {
+		conn.Close()
+		return nil, fmt.Errorf("invalid MQTT connection acknowledgement")
+	}
+	if resp[1] != 0 {
+		conn.Close()
+		return nil, fmt.Errorf("MQTT broker refused the connection with code %d", resp[1])
+	}
+
+	return c, nil
+}
+
+// appendMQTTString appends s to b prefixed by its length
+func appendMQTTString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+// write sends a packet with the fixed header byte header
+func (c *mqttClient) write(header byte, body []byte) error {
+	pkt := []byte{header}
+
+	// the remaining length is encoded 7 bits at a time
+	length := len(body)
+	for {
+		b := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			b |= 0x80
+		}
+		pkt = append(pkt, b)
+		if length == 0 {
+			break
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, err := c.conn.Write(append(pkt, body...))
+
+	return err
+}
+
+// read receives the next packet and returns its type and body
+func (c *mqttClient) read() (byte, []byte, error) {
+	header, err := c.r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return 0, nil, fmt.Errorf("invalid MQTT packet length")
+		}
+
+		b, err := c.r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		length += int(b&0x7f) * multiplier
+		multiplier *= 128
+
+		if b&0x80 == 0 {
+			break
+		}
+	}
+
+	body := make([]byte, length)
+	_, err = io.ReadFull(c.r, body)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return header >> 4, body, nil
+}
+
+// subscribe requests messages published to topics, the acknowledgement is handled by receive
+func (c *mqttClient) subscribe(topics ...string) error {
+	c.nextID++
+
+	body := binary.BigEndian.AppendUint16(nil, c.nextID)
+	for _, topic := range topics {
+		body = appendMQTTString(body, topic)
+		body = append(body, 0)
+	}
+
+	return c.write(mqttSubscribe<<4|0x02, body)
+}
+
+// publish sends payload to topic
+func (c *mqttClient) publish(topic string, payload []byte) error {
+	return c.write(mqttPublish<<4, append(appendMQTTString(nil, topic), payload...))
+}
+
+// receive calls handler for every message until ctx is cancelled or the connection fails
+func (c *mqttClient) receive(ctx context.Context, handler func(topic string, payload []byte)) error {
+	done := make(chan struct{})
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+
+	// the connection can be used again once receive returned
+	defer func() {
+		close(done)
+		wg.Wait()
+		c.conn.SetReadDeadline(time.Time{})
+	}()
+
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(mqttKeepAlive / 2)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				c.write(mqttPingReq<<4, nil)
+			case <-ctx.Done():
+				// interrupts the read in progress
+				c.conn.SetReadDeadline(time.Now())
+				return
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	for {
+		kind, body, err := c.read()
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("MQTT connection failed: %v", err)
+		}
+
+		switch kind {
+		case mqttSubAck:
+			if len(body) < 3 {
+				return fmt.Errorf("invalid MQTT subscription acknowledgement")
+			}
+			for _, code := range body[2:] {
+				if code == 0x80 {
+					return errors.New("MQTT broker rejected the subscription")
+				}
+			}
+
+		case mqttPublish:
+			// subscriptions use QoS 0 so messages are delivered without a packet id
+			if len(body) < 2 {
+				return fmt.Errorf("invalid MQTT message")
+			}
+
+			n := int(binary.BigEndian.Uint16(body))
+			if len(body) < 2+n {
+				return fmt.Errorf("invalid MQTT message")
+			}
+
+			handler(string(body[2:2+n]), body[2+n:])
+		}
+	}
+}
+
+// close disconnects from the broker
+func (c *mqttClient) close() error {
+	c.write(mqttDisconnect<<4, nil)
+	return c.conn.Close()
+}
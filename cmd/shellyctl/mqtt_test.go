@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeBroker accepts a single client and answers commands published to a relay the way a Gen 1 device would
+func fakeBroker(t *testing.T, state string) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not listen: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		srv := &mqttClient{conn: conn, r: bufio.NewReader(conn)}
+		for {
+			kind, body, err := srv.read()
+			if err != nil {
+				return
+			}
+
+			switch kind {
+			case mqttConnect:
+				srv.write(mqttConnAck<<4, []byte{0, 0})
+
+			case mqttSubscribe:
+				srv.write(mqttSubAck<<4, append(body[:2:2], 0))
+
+			case mqttPublish:
+				n := int(binary.BigEndian.Uint16(body))
+				topic, payload := string(body[2:2+n]), string(body[2+n:])
+
+				if payload == "toggle" {
+					payload = state
+				}
+				srv.publish(strings.TrimSuffix(topic, "/command"), []byte(payload))
+			}
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+func TestMQTTSwitchDevice(t *testing.T) {
+	defer func(p string, d time.Duration) { mqttTopicPrefix, mqttTimeout = p, d }(mqttTopicPrefix, mqttTimeout)
+	mqttTopicPrefix, mqttTimeout = "shellies", time.Second
+
+	client, err := dialMQTT(context.Background(), fakeBroker(t, "off"), "", "")
+	if err != nil {
+		t.Fatalf("could not connect: %v", err)
+	}
+	defer client.close()
+
+	var out bytes.Buffer
+	err = mqttSwitchDevice(context.Background(), client, "shellyplug-s-6B0A21", "on", &out)
+	if err != nil {
+		t.Fatalf("switching on failed: %v", err)
+	}
+
+	// the connection is reused for following devices
+	err = mqttSwitchDevice(context.Background(), client, "shellyplug-s-6B0A22", "toggle", &out)
+	if err != nil {
+		t.Fatalf("toggling failed: %v", err)
+	}
+
+	expected := "Device shellyplug-s-6B0A21 turned on\nDevice shellyplug-s-6B0A22 turned off\n"
+	if out.String() != expected {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+
+	out.Reset()
+	err = mqttSwitchDevice(context.Background(), nil, "shellyplug-s-6B0A21", "off", &out)
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if out.String() != "DRY RUN: PUBLISH shellies/shellyplug-s-6B0A21/relay/0/command off\n" {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+}
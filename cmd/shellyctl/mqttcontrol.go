@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/ripienaar/shellyctl"
+)
+
+var (
+	mqttControl     bool
+	mqttTopicPrefix string
+	mqttDeviceIDs   []string
+	mqttTimeout     time.Duration
+)
+
+// mqttBrokerFlags adds the flags used to connect to the MQTT broker
+func mqttBrokerFlags(cmd *fisk.CmdClause) *fisk.CmdClause {
+	cmd.Flag("mqtt-broker", "MQTT broker the devices are connected to").PlaceHolder("HOST:PORT").StringVar(&mqttBroker)
+	cmd.Flag("mqtt-username", "MQTT broker username").StringVar(&mqttUser)
+	cmd.Flag("mqtt-password", "MQTT broker password").StringVar(&mqttPass)
+	return cmd
+}
+
+// mqttControlFlags adds the flags that switch Gen 1 devices by publishing to their MQTT command topic
+func mqttControlFlags(cmd *fisk.CmdClause) *fisk.CmdClause {
+	mqttBrokerFlags(cmd)
+	cmd.Flag("mqtt-control", "Switches Gen 1 devices using MQTT instead of HTTP").UnNegatableBoolVar(&mqttControl)
+	cmd.Flag("mqtt-topic-prefix", "Topic prefix the devices publish below").Default("shellies").StringVar(&mqttTopicPrefix)
+	cmd.Flag("mqtt-device-id", "MQTT id of the device, like shellyplug-s-6B0A21, can be passed multiple times").StringsVar(&mqttDeviceIDs)
+	cmd.Flag("mqtt-timeout", "How long to wait for the device to confirm the change").Default("5s").DurationVar(&mqttTimeout)
+	return cmd
+}
+
+// mqttSwitch publishes command, one of on, off or toggle, to every --mqtt-device-id and waits for the
+// devices to report their new relay state
+func mqttSwitch(command string) error {
+	if mqttBroker == "" {
+		return fmt.Errorf("--mqtt-control requires --mqtt-broker")
+	}
+	if len(mqttDeviceIDs) == 0 {
+		return fmt.Errorf("--mqtt-control requires --mqtt-device-id")
+	}
+
+	var client *mqttClient
+	if !dryRun {
+		var err error
+		client, err = dialMQTT(ctx, mqttBroker, mqttUser, mqttPass)
+		if err != nil {
+			return err
+		}
+		defer client.close()
+	}
+
+	var errs []error
+	for _, id := range mqttDeviceIDs {
+		err := mqttSwitchDevice(ctx, client, id, command, output)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", id, err))
+		}
+	}
+
+	commandErr = errors.Join(errs...)
+
+	return commandErr
+}
+
+// mqttSwitchDevice publishes command to the device and waits for it to report the relay state, a nil
+// client only shows the message that would be published
+func mqttSwitchDevice(ctx context.Context, client *mqttClient, id string, command string, w io.Writer) error {
+	topic := fmt.Sprintf("%s/%s/relay/0", mqttTopicPrefix, id)
+
+	if client == nil {
+		fmt.Fprintf(w, "DRY RUN: PUBLISH %s/command %s\n", topic, command)
+		return nil
+	}
+
+	err := client.subscribe(topic)
+	if err != nil {
+		return err
+	}
+
+	err = client.publish(topic+"/command", []byte(command))
+	if err != nil {
+		return err
+	}
+
+	wctx, cancel := context.WithTimeout(ctx, mqttTimeout)
+	defer cancel()
+
+	var state string
+	err = client.receive(wctx, func(t string, payload []byte) {
+		if t != topic {
+			return
+		}
+
+		// retained messages might hold the previous state, toggles are confirmed by any report
+		if command == "toggle" || string(payload) == command {
+			state = string(payload)
+			cancel()
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case state != "":
+		fmt.Fprintf(w, "Device %s turned %s\n", id, state)
+		return nil
+
+	case ctx.Err() != nil:
+		return ctx.Err()
+
+	case command == "on":
+		return fmt.Errorf("%w: no confirmation received within %v", shellyctl.ErrRelayNotOn, mqttTimeout)
+
+	default:
+		return fmt.Errorf("no confirmation received within %v", mqttTimeout)
+	}
+}
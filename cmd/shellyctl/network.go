@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/choria-io/fisk"
+	"github.com/ripienaar/shellyctl"
+)
+
+var (
+	networkSSID   string
+	networkPass   string
+	networkReboot bool
+)
+
+func networkShowAction(_ *fisk.ParseContext) error {
+	return forEachDevice(networkShowDevice)
+}
+
+func networkShowDevice(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+	_, err := requireGen2(ctx, plug)
+	if err != nil {
+		return err
+	}
+
+	var cfg shellyctl.Gen2WiFiConfig
+	err = plug.RPC(ctx, "WiFi.GetConfig", nil, &cfg)
+	if err != nil {
+		return err
+	}
+
+	var status shellyctl.Gen2WiFiStatus
+	err = plug.RPC(ctx, "WiFi.GetStatus", nil, &status)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Shelly network information for %s\n", address)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "WiFi Status")
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "              Status: %s\n", status.Status)
+	fmt.Fprintf(w, "          IP Address: %s\n", status.StaIP)
+	fmt.Fprintf(w, "           WiFi SSID: %s\n", status.SSID)
+	fmt.Fprintf(w, "       WiFi Strength: %d\n", status.RSSI)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Station Configuration")
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "             Enabled: %s\n", stateBool(cfg.Sta.Enable))
+	fmt.Fprintf(w, "                SSID: %s\n", cfg.Sta.SSID)
+	fmt.Fprintf(w, "           IPv4 Mode: %s\n", cfg.Sta.IPv4Mode)
+	if cfg.Sta.IPv4Mode == "static" {
+		fmt.Fprintf(w, "          IP Address: %s\n", cfg.Sta.IP)
+		fmt.Fprintf(w, "             Netmask: %s\n", cfg.Sta.Netmask)
+		fmt.Fprintf(w, "             Gateway: %s\n", cfg.Sta.Gateway)
+		fmt.Fprintf(w, "         Name Server: %s\n", cfg.Sta.Resolver)
+	}
+	if cfg.Sta1.SSID != "" {
+		fmt.Fprintf(w, "       Fallback SSID: %s\n", cfg.Sta1.SSID)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Access Point Configuration")
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "             Enabled: %t\n", cfg.AP.Enable)
+	fmt.Fprintf(w, "                SSID: %s\n", cfg.AP.SSID)
+	fmt.Fprintf(w, "                Open: %s\n", warnBool(cfg.AP.IsOpen))
+	fmt.Fprintf(w, "             Clients: %d\n", status.APClientCount)
+
+	return nil
+}
+
+func networkSetAPAction(_ *fisk.ParseContext) error {
+	return forEachDevice(networkSetAPDevice)
+}
+
+func networkSetAPDevice(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+	err := setWiFiConfig(ctx, plug, map[string]any{"sta": stationConfig(networkSSID, networkPass)}, w)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Device %s will connect to WiFi network %s\n", address, networkSSID)
+
+	return nil
+}
+
+// stationConfig is the Gen 2 station configuration to connect to ssid, the network is open when pass is empty
+func stationConfig(ssid string, pass string) map[string]any {
+	sta := map[string]any{
+		"ssid":    ssid,
+		"is_open": pass == "",
+		"enable":  true,
+	}
+	if pass != "" {
+		sta["pass"] = pass
+	}
+
+	return sta
+}
+
+func networkEnableAPAction(_ *fisk.ParseContext) error {
+	return forEachDevice(func(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+		return networkToggleAPDevice(ctx, address, plug, true, w)
+	})
+}
+
+func networkDisableAPAction(_ *fisk.ParseContext) error {
+	return forEachDevice(func(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+		return networkToggleAPDevice(ctx, address, plug, false, w)
+	})
+}
+
+func networkToggleAPDevice(ctx context.Context, address string, plug shellyctl.Plug, enable bool, w io.Writer) error {
+	err := setWiFiConfig(ctx, plug, map[string]any{"ap": map[string]any{"enable": enable}}, w)
+	if err != nil {
+		return err
+	}
+
+	if enable {
+		fmt.Fprintf(w, "Access point enabled on %s\n", address)
+	} else {
+		fmt.Fprintf(w, "Access point disabled on %s\n", address)
+	}
+
+	return nil
+}
+
+func setWiFiConfig(ctx context.Context, plug shellyctl.Plug, cfg map[string]any, w io.Writer) error {
+	_, err := requireGen2(ctx, plug)
+	if err != nil {
+		return err
+	}
+
+	var res struct {
+		RestartRequired bool `json:"restart_required"`
+	}
+	err = plug.RPC(ctx, "WiFi.SetConfig", map[string]any{"config": cfg}, &res)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case networkReboot:
+		err = plug.RPC(ctx, "Shelly.Reboot", nil, &map[string]any{})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, "Device is rebooting")
+
+	case res.RestartRequired:
+		fmt.Fprintln(w, "The device must be restarted for the change to take effect")
+	}
+
+	return nil
+}
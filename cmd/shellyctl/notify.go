@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/ripienaar/shellyctl"
+)
+
+var (
+	notifyInterval   time.Duration
+	notifyOnCommand  string
+	notifyOffCommand string
+	notifyOnURL      string
+	notifyOffURL     string
+
+	// relayStates holds the last seen relay state of every device by address
+	relayStates   = map[string]bool{}
+	relayStatesMu sync.Mutex
+)
+
+// notification is the body posted to --on-turn-on-url and --on-turn-off-url
+type notification struct {
+	Address string    `json:"address"`
+	State   string    `json:"state"`
+	Time    time.Time `json:"time"`
+}
+
+func notifyAction(_ *fisk.ParseContext) error {
+	if notifyOnCommand == "" && notifyOffCommand == "" && notifyOnURL == "" && notifyOffURL == "" {
+		return fmt.Errorf("at least one of --on-turn-on, --on-turn-off, --on-turn-on-url or --on-turn-off-url is required")
+	}
+
+	for {
+		err := forEachDevice(notifyDevice)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			slog.Warn("Polling devices failed", "error", err)
+		}
+
+		if sleep(ctx, notifyInterval) != nil {
+			return nil
+		}
+	}
+}
+
+// notifyDevice polls the relay state and notifies when it differs from the previous poll, the first
+// poll of a device only records its state
+func notifyDevice(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+	relay, err := plug.RelayStatus(ctx)
+	if err != nil {
+		return err
+	}
+
+	relayStatesMu.Lock()
+	previous, seen := relayStates[address]
+	relayStates[address] = relay.IsOn
+	relayStatesMu.Unlock()
+
+	if !seen || previous == relay.IsOn {
+		return nil
+	}
+
+	state, command, url := "off", notifyOffCommand, notifyOffURL
+	if relay.IsOn {
+		state, command, url = "on", notifyOnCommand, notifyOnURL
+	}
+
+	fmt.Fprintf(w, "Device %s turned %s\n", address, state)
+
+	if command != "" {
+		err = runNotifyCommand(ctx, command, address, state, w)
+		if err != nil {
+			return fmt.Errorf("notification command failed: %w", err)
+		}
+	}
+
+	if url != "" {
+		err = postNotification(ctx, url, notification{Address: address, State: state, Time: time.Now().UTC()})
+		if err != nil {
+			return fmt.Errorf("notification to %s failed: %w", url, err)
+		}
+	}
+
+	return nil
+}
+
+// runNotifyCommand runs command using the shell, the device and its state are passed in the environment
+func runNotifyCommand(ctx context.Context, command string, address string, state string, w io.Writer) error {
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", command)
+	cmd.Env = append(os.Environ(), "SHELLY_ADDRESS="+address, "SHELLY_STATE="+state)
+	cmd.Stdout = w
+	cmd.Stderr = os.Stderr
+
+	return cmd.Run()
+}
+
+func postNotification(ctx context.Context, url string, n notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("received %s", resp.Status)
+	}
+
+	return nil
+}
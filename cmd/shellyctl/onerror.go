@@ -0,0 +1,61 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+var (
+	onErrorCommand string
+
+	// errorOutput receives the errors written by fisk, the last one is passed to --on-error
+	errorOutput = &errorRecorder{}
+
+	// onErrorPlaceholder refers to the message in the environment, the message can hold responses from devices and
+	// is never put in the script itself, already quoted placeholders are replaced as a whole
+	onErrorPlaceholder = strings.NewReplacer(`"{}"`, `"$SHELLYCTL_ERROR"`, `'{}'`, `"$SHELLYCTL_ERROR"`, `{}`, `"$SHELLYCTL_ERROR"`)
+)
+
+// errorRecorder writes errors to stderr and keeps the message of the last error a command failed with
+type errorRecorder struct {
+	last string
+	mu   sync.Mutex
+}
+
+func (e *errorRecorder) Write(p []byte) (int, error) {
+	if msg, ok := strings.CutPrefix(string(p), "shellyctl: error: "); ok {
+		e.mu.Lock()
+		e.last = strings.TrimSpace(msg)
+		e.mu.Unlock()
+	}
+
+	return os.Stderr.Write(p)
+}
+
+func (e *errorRecorder) message() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.last
+}
+
+// runOnError runs the --on-error command using the shell with msg in SHELLYCTL_ERROR, {} in the command refers to
+// that variable, failures are only logged so the exit status of shellyctl is not affected
+func runOnError(msg string) {
+	if onErrorCommand == "" {
+		return
+	}
+
+	cmd := exec.Command("/bin/sh", "-c", onErrorPlaceholder.Replace(onErrorCommand))
+	cmd.Env = append(os.Environ(), "SHELLYCTL_ERROR="+msg)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+
+	err := cmd.Run()
+	if err != nil {
+		slog.Warn("Running the --on-error command failed", "error", err)
+	}
+}
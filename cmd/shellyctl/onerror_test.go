@@ -0,0 +1,62 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunOnError(t *testing.T) {
+	t.Cleanup(func() { onErrorCommand, errorOutput = "", &errorRecorder{} })
+
+	out := filepath.Join(t.TempDir(), "error.txt")
+	onErrorCommand = `printf '%s|%s' "{}" "$SHELLYCTL_ERROR" > ` + out + `; exit 3`
+
+	errorOutput = &errorRecorder{}
+	errorOutput.Write([]byte("shellyctl: error: device unreachable\n"))
+
+	commandFailed(1)
+
+	res, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("command did not run: %v", err)
+	}
+	if string(res) != "device unreachable|device unreachable" {
+		t.Fatalf("unexpected command output %q", res)
+	}
+
+	errorOutput = &errorRecorder{}
+	commandFailed(2)
+
+	res, _ = os.ReadFile(out)
+	if string(res) != "exit status 2|exit status 2" {
+		t.Fatalf("unexpected command output without an error %q", res)
+	}
+}
+
+func TestRunOnErrorQuoting(t *testing.T) {
+	t.Cleanup(func() { onErrorCommand = "" })
+
+	dir := t.TempDir()
+	out := filepath.Join(dir, "error.txt")
+	msg := `bad response: $(touch ` + dir + `/a) "; touch ` + dir + `/b; " '; touch ` + dir + `/c; '`
+
+	for _, cmd := range []string{`printf '%s' {} > `, `printf '%s' "{}" > `, `printf '%s' '{}' > `} {
+		onErrorCommand = cmd + out
+		runOnError(msg)
+
+		res, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatalf("command did not run: %v", err)
+		}
+		if string(res) != msg {
+			t.Fatalf("%s: unexpected command output %q", cmd, res)
+		}
+
+		for _, f := range []string{"a", "b", "c"} {
+			if _, err := os.Stat(filepath.Join(dir, f)); err == nil {
+				t.Fatalf("%s: the error message was executed", cmd)
+			}
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+var (
+	otelEndpoint string
+
+	// otelMetrics is set when energy metrics are exported using OpenTelemetry
+	otelMetrics *otelExporter
+)
+
+// otelExporter records energy readings as OTLP gauges, readings are only pushed when flush is called
+type otelExporter struct {
+	reader   *sdkmetric.ManualReader
+	exporter sdkmetric.Exporter
+	power    metric.Float64Gauge
+	total    metric.Float64Gauge
+	relayOn  metric.Float64Gauge
+}
+
+func newOtelExporter(ctx context.Context, endpoint string) (*otelExporter, error) {
+	exporter, err := otlpmetrichttp.New(ctx, otlpmetrichttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, err
+	}
+
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("github.com/ripienaar/shellyctl")
+
+	res := &otelExporter{reader: reader, exporter: exporter}
+
+	res.power, err = meter.Float64Gauge("shelly.power.watt", metric.WithDescription("Current power usage"), metric.WithUnit("W"))
+	if err != nil {
+		return nil, err
+	}
+
+	res.total, err = meter.Float64Gauge("shelly.energy.kwh_total", metric.WithDescription("Total energy consumed"), metric.WithUnit("kWh"))
+	if err != nil {
+		return nil, err
+	}
+
+	res.relayOn, err = meter.Float64Gauge("shelly.relay.on", metric.WithDescription("1 when the relay is on"))
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// record stores a reading for the device at address, labels are added as attributes and replace the default
+// device attribute holding the address
+func (e *otelExporter) record(ctx context.Context, address string, labels map[string]string, power float64, total float64, relayOn float64) {
+	attributes := map[string]string{"device": address}
+	for k, v := range labels {
+		attributes[k] = v
+	}
+
+	var attrs []attribute.KeyValue
+	for _, k := range sortedKeys(attributes) {
+		attrs = append(attrs, attribute.String(k, attributes[k]))
+	}
+	opt := metric.WithAttributes(attrs...)
+
+	e.power.Record(ctx, power, opt)
+	e.total.Record(ctx, total, opt)
+	e.relayOn.Record(ctx, relayOn, opt)
+}
+
+// flush pushes all recorded readings to the collector
+func (e *otelExporter) flush(ctx context.Context) error {
+	var rm metricdata.ResourceMetrics
+
+	err := e.reader.Collect(ctx, &rm)
+	if err != nil {
+		return err
+	}
+
+	return e.exporter.Export(ctx, &rm)
+}
+
+func (e *otelExporter) shutdown(ctx context.Context) error {
+	return e.exporter.Shutdown(ctx)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/choria-io/fisk"
+)
+
+var (
+	outputFile   string
+	outputAppend bool
+	jsonCompact  bool
+
+	// output is where command output is written, stdout unless --output-file is set
+	output = os.Stdout
+)
+
+// configureOutput opens the file set using --output-file, the file is truncated unless --output-append is set,
+// --json-compact, --output-jq and --json-path imply --json
+func configureOutput(_ *fisk.ParseContext) error {
+	if outputJQ != "" && outputJSONPath != "" {
+		return fmt.Errorf("--output-jq and --json-path cannot be combined")
+	}
+
+	// these also apply to other JSON based formats like Choria metrics
+	if (jsonCompact || outputJQ != "" || outputJSONPath != "") && !choriaFormat {
+		jsonFormat = true
+	}
+
+	if outputFile == "" {
+		if outputAppend {
+			return fmt.Errorf("--output-append requires --output-file")
+		}
+
+		return nil
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if outputAppend {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+
+	f, err := os.OpenFile(outputFile, flags, 0644)
+	if err != nil {
+		return err
+	}
+
+	output = f
+
+	return nil
+}
+
+// writeJSON writes v as JSON, on a single line when --json-compact is set, when --output-jq is set every
+// result of the expression is written instead and when --json-path is set only the value at the path, in watch
+// mode --timestamps adds a timestamp field
+func writeJSON(w io.Writer, v any) error {
+	v = withTimestamp(v)
+
+	switch {
+	case outputJQ != "":
+		return writeJQ(w, v)
+	case outputJSONPath != "":
+		return writeJSONPath(w, v)
+	}
+
+	j, err := marshalOutput(v)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w, string(j))
+
+	return nil
+}
+
+func marshalOutput(v any) ([]byte, error) {
+	if jsonCompact {
+		return json.Marshal(v)
+	}
+
+	return json.MarshalIndent(v, "", "  ")
+}
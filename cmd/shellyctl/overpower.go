@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/choria-io/fisk"
+	"github.com/ripienaar/shellyctl"
+)
+
+var overpowerWatts int
+
+func overpowerGetAction(_ *fisk.ParseContext) error {
+	return forEachDevice(overpowerGetDevice)
+}
+
+func overpowerGetDevice(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+	_, err := requireGen1(ctx, plug)
+	if err != nil {
+		return err
+	}
+
+	settings, err := plug.Settings(ctx)
+	if err != nil {
+		return err
+	}
+
+	limit, ok := lookupPath(settings, gen1Settings["max_power"].Read)
+	if !ok {
+		return fmt.Errorf("device does not support overpower protection")
+	}
+
+	relay, err := plug.RelayStatus(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Overpower protection for %s\n", address)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "           Threshold: %v Watt\n", limit)
+	fmt.Fprintf(w, "           Triggered: %s\n", warnBool(relay.Overpower))
+
+	return nil
+}
+
+func overpowerSetAction(_ *fisk.ParseContext) error {
+	return forEachDevice(overpowerSetDevice)
+}
+
+func overpowerSetDevice(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+	_, err := requireGen1(ctx, plug)
+	if err != nil {
+		return err
+	}
+
+	setting := gen1Settings["max_power"]
+	_, err = plug.UpdateSettings(ctx, setting.Component, map[string]string{setting.Param: strconv.Itoa(overpowerWatts)})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Overpower protection threshold on %s set to %d Watt\n", address, overpowerWatts)
+
+	return nil
+}
+
+func overpowerResetAction(_ *fisk.ParseContext) error {
+	return forEachDevice(overpowerResetDevice)
+}
+
+func overpowerResetDevice(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+	_, err := requireGen1(ctx, plug)
+	if err != nil {
+		return err
+	}
+
+	relay, err := plug.RelayStatus(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !relay.Overpower {
+		fmt.Fprintf(w, "Overpower protection is not triggered on %s\n", address)
+		return nil
+	}
+
+	// the overpower state clears once the relay is turned on again
+	_, err = plug.TurnOn(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Overpower state cleared and device %s turned on\n", address)
+
+	return nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/choria-io/fisk"
+)
+
+var pidFile string
+
+// configurePidFile writes the process ID to the file set using --write-pid-file, the file is removed by the
+// exit hooks that run when the command completes, including after an interrupt, and from terminate
+func configurePidFile(_ *fisk.ParseContext) error {
+	if pidFile == "" {
+		return nil
+	}
+
+	err := os.WriteFile(pidFile, []byte(strconv.Itoa(os.Getpid())+"\n"), 0644)
+	if err != nil {
+		return err
+	}
+
+	atExit(func() { os.Remove(pidFile) })
+
+	return nil
+}
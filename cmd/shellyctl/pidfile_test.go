@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPidFileRemovedAfterInterrupt(t *testing.T) {
+	prevCtx, prevOutput := ctx, output
+	t.Cleanup(func() { ctx, output, pidFile = prevCtx, prevOutput, "" })
+
+	devNull, err := openDevNull()
+	if err != nil {
+		t.Fatalf("could not open output: %v", err)
+	}
+	defer devNull.Close()
+	output = devNull
+
+	pidFile = filepath.Join(t.TempDir(), "shellyctl.pid")
+	err = configurePidFile(nil)
+	if err != nil {
+		t.Fatalf("could not write pid file: %v", err)
+	}
+	if _, err := os.Stat(pidFile); err != nil {
+		t.Fatalf("pid file was not written: %v", err)
+	}
+
+	var cancel context.CancelFunc
+	ctx, cancel = context.WithCancel(context.Background())
+
+	updated := make(chan struct{}, 1)
+	done := make(chan error)
+	go func() {
+		done <- watchLoop(time.Hour, func() error {
+			select {
+			case updated <- struct{}{}:
+			default:
+			}
+			return nil
+		})
+	}()
+
+	<-updated
+	cancel()
+
+	err = <-done
+	if err != nil {
+		t.Fatalf("watch failed: %v", err)
+	}
+
+	completed()
+
+	if _, err := os.Stat(pidFile); !os.IsNotExist(err) {
+		t.Fatalf("pid file was not removed: %v", err)
+	}
+}
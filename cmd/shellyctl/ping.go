@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/ripienaar/shellyctl"
+)
+
+var (
+	pingCount    int
+	pingInterval time.Duration
+
+	// errAllPingsFailed exits with code 2 unless --machine-exit-code is set
+	errAllPingsFailed = errors.New("all pings failed")
+)
+
+func pingAction(_ *fisk.ParseContext) error {
+	var sent, failed atomic.Int64
+
+	err := forEachDevice(func(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+		return pingDevice(ctx, plug, &sent, &failed, w)
+	})
+	// devices that could not be reached to detect their type failed every ping
+	if err != nil && !errors.Is(err, shellyctl.ErrDeviceUnreachable) {
+		return err
+	}
+
+	switch {
+	case err == nil && failed.Load() == 0:
+		return nil
+
+	case failed.Load() == sent.Load():
+		if err == nil {
+			err = shellyctl.ErrDeviceUnreachable
+		}
+		commandErr = fmt.Errorf("%w: %w", errAllPingsFailed, err)
+
+	default:
+		commandErr = errors.Join(fmt.Errorf("%d of %d pings failed", failed.Load(), sent.Load()), err)
+	}
+
+	return commandErr
+}
+
+func pingDevice(ctx context.Context, plug shellyctl.Plug, sent *atomic.Int64, failed *atomic.Int64, w io.Writer) error {
+	var total time.Duration
+	var ok int
+
+	for i := 0; i < pingCount; i++ {
+		if i > 0 {
+			err := sleep(ctx, pingInterval)
+			if err != nil {
+				return err
+			}
+		}
+
+		sent.Add(1)
+		start := time.Now()
+		// the device information is cached so it is requested directly
+		var nfo shellyctl.DeviceInfo
+		err := plug.Get(ctx, "shelly", &nfo)
+		rtt := time.Since(start)
+
+		if err != nil {
+			failed.Add(1)
+			fmt.Fprintf(w, "Device unreachable: %v\n", err)
+			continue
+		}
+
+		ok++
+		total += rtt
+		fmt.Fprintf(w, "Device reachable: %s\n", formatLatency(rtt))
+	}
+
+	if pingCount > 1 {
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "%d pings sent, %d failed", pingCount, pingCount-ok)
+		if ok > 0 {
+			fmt.Fprintf(w, ", average %s", formatLatency(total/time.Duration(ok)))
+		}
+		fmt.Fprintln(w)
+	}
+
+	return nil
+}
+
+func formatLatency(d time.Duration) string {
+	return fmt.Sprintf("%.1fms", float64(d.Microseconds())/1000)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ripienaar/shellyctl"
+)
+
+func TestPingAction(t *testing.T) {
+	prevCtx, prevSim, prevOutput := ctx, simulator, output
+	t.Cleanup(func() {
+		ctx, simulator, output, addresses, commandErr = prevCtx, prevSim, prevOutput, nil, nil
+		pingCount, pingInterval = 0, 0
+	})
+
+	devNull, err := openDevNull()
+	if err != nil {
+		t.Fatalf("could not open output: %v", err)
+	}
+	defer devNull.Close()
+
+	ctx, output = context.Background(), devNull
+	pingCount, pingInterval = 2, time.Millisecond
+
+	simulator = httptest.NewServer(newSimulatedPlug())
+	defer simulator.Close()
+
+	addresses = []string{"ping-ok.example.net"}
+	err = pingAction(nil)
+	if err != nil {
+		t.Fatalf("ping failed: %v", err)
+	}
+
+	// a device that is not listening fails every ping
+	simulator = httptest.NewServer(newSimulatedPlug())
+	simulator.Close()
+
+	addresses = []string{"ping-down.example.net"}
+	err = pingAction(nil)
+	if !errors.Is(err, errAllPingsFailed) || !errors.Is(err, shellyctl.ErrDeviceUnreachable) {
+		t.Fatalf("expected all pings to fail: %v", err)
+	}
+	if !errors.Is(commandErr, errAllPingsFailed) {
+		t.Fatalf("expected the command error to be recorded: %v", commandErr)
+	}
+	if code := exitCodeFor(commandErr); code != exitUnreachable {
+		t.Fatalf("expected the unreachable exit code got %d", code)
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/ripienaar/shellyctl"
+)
+
+var (
+	provisionAPSSID    string
+	provisionAPAddress string
+	provisionSSID      string
+	provisionPass      string
+	provisionWait      time.Duration
+)
+
+func provisionAction(_ *fisk.ParseContext) error {
+	if provisionAPSSID != "" {
+		err := joinNetwork(ctx, provisionAPSSID)
+		if err != nil {
+			return err
+		}
+	}
+
+	fingerprints, err := loadFingerprints()
+	if err != nil {
+		return err
+	}
+
+	var dryRunOut io.Writer
+	if dryRun {
+		dryRunOut = output
+	}
+
+	plug, err := newDevice(provisionAPAddress, fingerprints, dryRunOut)
+	if err != nil {
+		return err
+	}
+
+	nfo, err := plug.Info(ctx)
+	if err != nil {
+		return err
+	}
+
+	hostname, err := deviceHostname(ctx, plug, nfo)
+	if err != nil {
+		return err
+	}
+
+	if nfo.Generation() == 1 {
+		sta := map[string]string{"enabled": "1", "ssid": provisionSSID}
+		if provisionPass != "" {
+			sta["key"] = provisionPass
+		}
+
+		// Gen 1 devices leave access point mode and join the network as soon as the settings are saved
+		_, err = plug.UpdateSettings(ctx, "sta", sta)
+	} else {
+		networkReboot = true
+		err = setWiFiConfig(ctx, plug, map[string]any{"sta": stationConfig(provisionSSID, provisionPass)}, io.Discard)
+	}
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		return nil
+	}
+
+	fmt.Fprintf(output, "Device %s will connect to WiFi network %s\n", hostname, provisionSSID)
+
+	if provisionWait == 0 {
+		return nil
+	}
+
+	address := fmt.Sprintf("%s.local", hostname)
+	ip, err := waitForHostname(ctx, address, provisionWait)
+	if err != nil {
+		return err
+	}
+
+	plug, err = newDevice(address, fingerprints, nil)
+	if err != nil {
+		return err
+	}
+
+	err = waitForPlug(ctx, plug, provisionWait)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(output, "Device %s is reachable at %s\n", address, ip)
+
+	return nil
+}
+
+// deviceHostname is the name the device announces using mDNS once it joined the network
+func deviceHostname(ctx context.Context, plug shellyctl.Plug, nfo *shellyctl.DeviceInfo) (string, error) {
+	if nfo.Generation() > 1 {
+		var res struct {
+			ID string `json:"id"`
+		}
+
+		err := plug.RPC(ctx, "Shelly.GetDeviceInfo", nil, &res)
+		if err != nil {
+			return "", err
+		}
+
+		return res.ID, nil
+	}
+
+	settings, err := plug.Settings(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	device, _ := settings["device"].(map[string]any)
+	hostname, _ := device["hostname"].(string)
+	if hostname == "" {
+		return "", fmt.Errorf("device settings do not include a hostname")
+	}
+
+	return hostname, nil
+}
+
+// joinNetwork connects this machine to the access point of a new device, this requires NetworkManager
+func joinNetwork(ctx context.Context, ssid string) error {
+	nmcli, err := exec.LookPath("nmcli")
+	if err != nil {
+		return fmt.Errorf("joining %s requires NetworkManager, connect to it manually and omit --ap-ssid", ssid)
+	}
+
+	out, err := exec.CommandContext(ctx, nmcli, "device", "wifi", "connect", ssid).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("could not join %s: %v: %s", ssid, err, strings.TrimSpace(string(out)))
+	}
+
+	slog.Info("Joined device access point", "ssid", ssid)
+
+	return nil
+}
+
+// waitForHostname resolves hostname until it succeeds or timeout passes, the first address found is returned
+func waitForHostname(ctx context.Context, hostname string, timeout time.Duration) (string, error) {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		addrs, err := net.DefaultResolver.LookupHost(ctx, hostname)
+		if err == nil && len(addrs) > 0 {
+			return addrs[0], nil
+		}
+
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("%s did not appear on the network within %v: %w", hostname, timeout, err)
+		}
+
+		slog.Info("Waiting for device to join the network", "hostname", hostname)
+		err = sleep(ctx, 2*time.Second)
+		if err != nil {
+			return "", err
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"github.com/choria-io/fisk"
+	"golang.org/x/time/rate"
+)
+
+var (
+	rateLimit float64
+
+	// requestLimiter limits requests to all devices, shared by all workers, nil when not limited
+	requestLimiter *rate.Limiter
+)
+
+// configureRateLimit sets up the limiter used when --rate-limit is set
+func configureRateLimit(_ *fisk.ParseContext) error {
+	if rateLimit > 0 {
+		requestLimiter = rate.NewLimiter(rate.Limit(rateLimit), 1)
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/choria-io/fisk"
+	"github.com/ripienaar/shellyctl"
+)
+
+var rollerPosition int
+
+// requireRoller ensures that plug is a device in roller mode
+func requireRoller(ctx context.Context, plug shellyctl.Plug) (shellyctl.Roller, error) {
+	roller, ok := plug.(shellyctl.Roller)
+	if ok {
+		return roller, nil
+	}
+
+	nfo, err := plug.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	model := nfo.Type
+	if nfo.Generation() > 1 {
+		model = nfo.Model
+	}
+
+	return nil, fmt.Errorf("this command requires a device in roller mode, %s is not a roller", model)
+}
+
+// rollerAction creates a deviceAction that calls move on a roller and reports the resulting state
+func rollerAction(verb string, move func(context.Context, shellyctl.Roller) (*shellyctl.RollerState, error)) deviceAction {
+	return func(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+		roller, err := requireRoller(ctx, plug)
+		if err != nil {
+			return err
+		}
+
+		state, err := move(ctx, roller)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(w, "Device %s %s, currently at %d%%\n", address, verb, state.CurrentPos)
+
+		return nil
+	}
+}
+
+func rollerOpenAction(_ *fisk.ParseContext) error {
+	return forEachDevice(rollerAction("opening", func(ctx context.Context, roller shellyctl.Roller) (*shellyctl.RollerState, error) {
+		return roller.Open(ctx)
+	}))
+}
+
+func rollerCloseAction(_ *fisk.ParseContext) error {
+	return forEachDevice(rollerAction("closing", func(ctx context.Context, roller shellyctl.Roller) (*shellyctl.RollerState, error) {
+		return roller.Close(ctx)
+	}))
+}
+
+func rollerStopAction(_ *fisk.ParseContext) error {
+	return forEachDevice(rollerAction("stopped", func(ctx context.Context, roller shellyctl.Roller) (*shellyctl.RollerState, error) {
+		return roller.Stop(ctx)
+	}))
+}
+
+func rollerPositionAction(_ *fisk.ParseContext) error {
+	if rollerPosition < 0 || rollerPosition > 100 {
+		return fmt.Errorf("position must be between 0 and 100")
+	}
+
+	return forEachDevice(rollerPositionDevice)
+}
+
+func rollerPositionDevice(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+	action := rollerAction(fmt.Sprintf("moving to %d%%", rollerPosition), func(ctx context.Context, roller shellyctl.Roller) (*shellyctl.RollerState, error) {
+		return roller.SetPosition(ctx, rollerPosition)
+	})
+
+	return action(ctx, address, plug, w)
+}
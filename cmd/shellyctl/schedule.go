@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/ripienaar/shellyctl"
+)
+
+var (
+	scheduleIn     time.Duration
+	scheduleAction string
+)
+
+func scheduleOnceAction(_ *fisk.ParseContext) error {
+	if scheduleIn < time.Minute {
+		return fmt.Errorf("--in must be at least 1 minute")
+	}
+
+	return forEachDevice(scheduleOnceDevice)
+}
+
+func scheduleOnceDevice(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+	nfo, err := plug.Info(ctx)
+	if err != nil {
+		return err
+	}
+
+	on := scheduleAction == "on"
+	at := time.Now().Add(scheduleIn).Truncate(time.Second)
+
+	if nfo.Generation() > 1 {
+		return scheduleOnceGen2(ctx, address, plug, on, at, w)
+	}
+
+	// Gen 1 devices have no one-off schedules, the relay is set to the opposite state and flips after the timer
+	_, err = plug.TurnWithTimer(ctx, !on, scheduleIn.Truncate(time.Second))
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Device %s turned %s, it will turn %s at %s using a timer\n", address, onOffString(!on), onOffString(on), at.Format(time.DateTime+" MST"))
+
+	return nil
+}
+
+// scheduleOnceGen2 creates a schedule that runs at the time at, schedules use the time zone of the device and as
+// the timespec includes the day and month the schedule only repeats a year later
+func scheduleOnceGen2(ctx context.Context, address string, plug shellyctl.Plug, on bool, at time.Time, w io.Writer) error {
+	var cfg struct {
+		Location struct {
+			TZ string `json:"tz"`
+		} `json:"location"`
+	}
+	err := plug.RPC(ctx, "Sys.GetConfig", nil, &cfg)
+	if err != nil {
+		return err
+	}
+
+	if cfg.Location.TZ != "" {
+		loc, err := time.LoadLocation(cfg.Location.TZ)
+		if err != nil {
+			return fmt.Errorf("unknown device time zone %q: %v", cfg.Location.TZ, err)
+		}
+		at = at.In(loc)
+	}
+
+	params := map[string]any{
+		"enable":   true,
+		"timespec": fmt.Sprintf("%d %d %d %d %d *", at.Second(), at.Minute(), at.Hour(), at.Day(), int(at.Month())),
+		"calls": []map[string]any{
+			{"method": "Switch.Set", "params": map[string]any{"id": 0, "on": on}},
+		},
+	}
+
+	var res struct {
+		ID int `json:"id"`
+	}
+	err = plug.RPC(ctx, "Schedule.Create", params, &res)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Device %s will turn %s at %s using schedule %d\n", address, onOffString(on), at.Format(time.DateTime+" MST"), res.ID)
+
+	return nil
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/choria-io/fisk"
+	"github.com/ripienaar/shellyctl"
+)
+
+// gen1Setting describes how a setting is read from /settings and how it is updated
+type gen1Setting struct {
+	Component   string // path below /settings used to update the setting
+	Param       string // query parameter used to update the setting
+	Read        string // dotted path to the setting in the /settings response
+	Description string
+}
+
+var (
+	settingKey   string
+	settingValue string
+
+	gen1Settings = map[string]gen1Setting{
+		"name":               {"", "name", "name", "Device name"},
+		"max_power":          {"relay/0", "max_power", "max_power", "Overpower protection threshold in Watt"},
+		"led_status_disable": {"", "led_status_disable", "led_status_disable", "Disables the WiFi status LED"},
+		"led_power_disable":  {"", "led_power_disable", "led_power_disable", "Disables the power status LED"},
+		"discoverable":       {"", "discoverable", "discoverable", "Allows the device to be discovered on the network"},
+		"timezone":           {"", "timezone", "timezone", "Timezone of the device"},
+		"tzautodetect":       {"", "tzautodetect", "tzautodetect", "Detect the timezone automatically"},
+		"sntp_server":        {"", "sntp_server", "sntp.server", "Time server to synchronize with"},
+		"mqtt_enable":        {"", "mqtt_enable", "mqtt.enable", "Enables MQTT"},
+		"mqtt_server":        {"", "mqtt_server", "mqtt.server", "MQTT server address"},
+		"coiot_enable":       {"", "coiot_enable", "coiot.enabled", "Enables CoIoT"},
+		"relay_name":         {"relay/0", "name", "relays.0.name", "Name of the relay"},
+		"default_state":      {"relay/0", "default_state", "relays.0.default_state", "State of the relay after power on (off, on, last)"},
+		"auto_on":            {"relay/0", "auto_on", "relays.0.auto_on", "Seconds after which the relay turns on again, 0 disables"},
+		"auto_off":           {"relay/0", "auto_off", "relays.0.auto_off", "Seconds after which the relay turns off again, 0 disables"},
+		"schedule":           {"relay/0", "schedule", "relays.0.schedule", "Enables the relay schedule"},
+		"schedule_rules":     {"relay/0", "schedule_rules", "relays.0.schedule_rules", "Comma separated list of schedule rules"},
+	}
+)
+
+func gen1SettingNames() []string {
+	var names []string
+	for name := range gen1Settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// gen1SettingsHelp describes all known settings for use in the help output
+func gen1SettingsHelp() string {
+	var help strings.Builder
+
+	help.WriteString("Known settings:\n\n")
+	for _, name := range gen1SettingNames() {
+		fmt.Fprintf(&help, "  %s: %s\n", name, gen1Settings[name].Description)
+	}
+
+	return help.String()
+}
+
+// lookupPath finds a value in nested maps and lists using a dotted path like relays.0.name
+func lookupPath(data any, path string) (any, bool) {
+	for _, part := range strings.Split(path, ".") {
+		switch d := data.(type) {
+		case map[string]any:
+			v, ok := d[part]
+			if !ok {
+				return nil, false
+			}
+			data = v
+
+		case []any:
+			idx, err := strconv.Atoi(part)
+			if err != nil || idx < 0 || idx >= len(d) {
+				return nil, false
+			}
+			data = d[idx]
+
+		default:
+			return nil, false
+		}
+	}
+
+	return data, true
+}
+
+func settingsGetAction(_ *fisk.ParseContext) error {
+	return forEachDevice(settingsGetDevice)
+}
+
+func settingsGetDevice(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+	_, err := requireGen1(ctx, plug)
+	if err != nil {
+		return err
+	}
+
+	settings, err := plug.Settings(ctx)
+	if err != nil {
+		return err
+	}
+
+	values := map[string]any{}
+	if settingKey == "" {
+		for name, s := range gen1Settings {
+			v, ok := lookupPath(settings, s.Read)
+			if ok {
+				values[name] = v
+			}
+		}
+	} else {
+		path := settingKey
+		if s, ok := gen1Settings[settingKey]; ok {
+			path = s.Read
+		}
+
+		v, ok := lookupPath(settings, path)
+		if !ok {
+			return fmt.Errorf("unknown setting %q, known settings are: %s", settingKey, strings.Join(gen1SettingNames(), ", "))
+		}
+		values[settingKey] = v
+	}
+
+	if jsonFormat {
+		if settingKey == "" {
+			return writeJSON(w, values)
+		}
+
+		return writeJSON(w, values[settingKey])
+	}
+
+	var names []string
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(w, "Shelly device settings for %s\n", address)
+	fmt.Fprintln(w)
+	for _, name := range names {
+		fmt.Fprintf(w, "%20s: %v\n", name, values[name])
+	}
+
+	return nil
+}
+
+func settingsSetAction(_ *fisk.ParseContext) error {
+	return forEachDevice(settingsSetDevice)
+}
+
+func settingsSetDevice(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+	setting, ok := gen1Settings[settingKey]
+	if !ok {
+		return fmt.Errorf("unknown setting %q, known settings are: %s", settingKey, strings.Join(gen1SettingNames(), ", "))
+	}
+
+	_, err := requireGen1(ctx, plug)
+	if err != nil {
+		return err
+	}
+
+	_, err = plug.UpdateSettings(ctx, setting.Component, map[string]string{setting.Param: settingValue})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Setting %s on %s set to %s\n", settingKey, address, settingValue)
+
+	return nil
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/ripienaar/shellyctl"
+)
+
+const (
+	// simulatedAddress is used when --simulate is given without --address
+	simulatedAddress = "simulator"
+	// simulatedPower is the power in Watt used by the simulated device while it is on
+	simulatedPower = 42.5
+)
+
+var (
+	simulate bool
+
+	// simulator serves the simulated device when --simulate is set, all devices are connected to it
+	simulator *httptest.Server
+)
+
+// configureSimulator starts the simulated device, it runs until the process exits
+func configureSimulator(_ *fisk.ParseContext) error {
+	if !simulate {
+		return nil
+	}
+
+	simulator = httptest.NewServer(newSimulatedPlug())
+	atExit(simulator.Close)
+
+	if len(addresses) == 0 {
+		addresses = []string{simulatedAddress}
+	}
+
+	slog.Info("Simulating a Shelly Plug S", "url", simulator.URL)
+
+	return nil
+}
+
+// simulatedPlug is an in memory Gen 1 Shelly Plug S that implements the endpoints used by shellyctl
+type simulatedPlug struct {
+	mu sync.Mutex
+
+	started       time.Time
+	updated       time.Time
+	isOn          bool
+	source        string
+	timerStarted  int64
+	timerDuration int64
+	totalWattMins float64
+	settings      map[string]any
+	actions       map[string][]shellyctl.Gen1Action
+}
+
+func newSimulatedPlug() *simulatedPlug {
+	now := time.Now()
+
+	return &simulatedPlug{
+		started:       now,
+		updated:       now,
+		source:        "init",
+		totalWattMins: 1234567,
+		settings: map[string]any{
+			"name":               "Simulated Plug",
+			"max_power":          2500.0,
+			"led_status_disable": false,
+			"led_power_disable":  false,
+			"discoverable":       true,
+			"timezone":           "UTC",
+			"tzautodetect":       true,
+			"sntp":               map[string]any{"server": "time.google.com", "enabled": true},
+			"mqtt":               map[string]any{"enable": false, "server": "192.168.33.3:1883", "id": "shellyplug-s-51A0C0"},
+			"coiot":              map[string]any{"enabled": true},
+			"relays": []any{map[string]any{
+				"name":           nil,
+				"default_state":  "off",
+				"auto_on":        0.0,
+				"auto_off":       0.0,
+				"schedule":       false,
+				"schedule_rules": []any{},
+				"max_power":      2500.0,
+			}},
+		},
+		actions: map[string][]shellyctl.Gen1Action{
+			"btn_on_url":  {{Index: 0, URLs: []string{""}}},
+			"btn_off_url": {{Index: 0, URLs: []string{""}}},
+			"out_on_url":  {{Index: 0, URLs: []string{""}}},
+			"out_off_url": {{Index: 0, URLs: []string{""}}},
+		},
+	}
+}
+
+func (s *simulatedPlug) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.advance()
+
+	q := r.URL.Query()
+
+	var res any
+	switch strings.Trim(r.URL.Path, "/") {
+	case "shelly":
+		res = shellyctl.DeviceInfo{Type: "SHPLG-S", MAC: "C45BBE51A0C0", FW: "20230913-112003/v1.14.0-gcb84623", LongID: 1}
+
+	case "status":
+		res = s.status()
+
+	case "relay/0":
+		s.switchRelay(q)
+		res = s.relay()
+
+	case "settings":
+		s.updateSettings("", q)
+		res = s.settings
+
+	case "settings/relay/0":
+		s.updateSettings("relay/0", q)
+		res = s.settings["relays"].([]any)[0]
+
+	case "settings/actions":
+		s.updateAction(q)
+		res = shellyctl.Gen1ActionSettings{Actions: s.actions}
+
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+// advance accumulates the energy used since the last request and expires the timer
+func (s *simulatedPlug) advance() {
+	now := time.Now()
+
+	if s.isOn {
+		s.totalWattMins += simulatedPower * now.Sub(s.updated).Minutes()
+	}
+	s.updated = now
+
+	if s.timerDuration > 0 && now.Unix() >= s.timerStarted+s.timerDuration {
+		s.isOn = !s.isOn
+		s.source = "timer"
+		s.timerStarted, s.timerDuration = 0, 0
+	}
+}
+
+func (s *simulatedPlug) switchRelay(q url.Values) {
+	turn := q.Get("turn")
+	if turn == "" {
+		return
+	}
+
+	switch turn {
+	case "on":
+		s.isOn = true
+	case "off":
+		s.isOn = false
+	case "toggle":
+		s.isOn = !s.isOn
+	}
+	s.source = "http"
+	s.timerStarted, s.timerDuration = 0, 0
+
+	timer, _ := strconv.ParseInt(q.Get("timer"), 10, 64)
+	if timer > 0 {
+		s.timerStarted, s.timerDuration = time.Now().Unix(), timer
+	}
+}
+
+func (s *simulatedPlug) relay() shellyctl.Relay {
+	relay := shellyctl.Relay{
+		IsOn:          s.isOn,
+		HasTimer:      s.timerDuration > 0,
+		TimerStarted:  s.timerStarted,
+		TimerDuration: s.timerDuration,
+		Source:        s.source,
+	}
+	if relay.HasTimer {
+		relay.TimerRemaining = s.timerStarted + s.timerDuration - time.Now().Unix()
+	}
+
+	return relay
+}
+
+func (s *simulatedPlug) status() shellyctl.DeviceStatus {
+	now := time.Now()
+
+	power := 0.0
+	if s.isOn {
+		power = simulatedPower
+	}
+
+	return shellyctl.DeviceStatus{
+		WiFi:     shellyctl.WiFiStatus{Connected: true, SSID: "simulated", IP: "192.168.1.10", RSSI: -60},
+		Time:     now.Format("15:04"),
+		Unixtime: now.Unix(),
+		MAC:      "C45BBE51A0C0",
+		Update:   shellyctl.UpdateStatus{Status: "idle", NewVersion: "20230913-112003/v1.14.0-gcb84623", OldVersion: "20230913-112003/v1.14.0-gcb84623"},
+		Uptime:   int64(now.Sub(s.started).Seconds()),
+		Relays:   []shellyctl.Relay{s.relay()},
+		Meters: []shellyctl.Meter{{
+			Power:     power,
+			IsValid:   true,
+			Timestamp: now.Unix(),
+			Counters:  []float64{power, power, power},
+			Total:     int64(s.totalWattMins),
+		}},
+		RamTotal:    52064,
+		RamFree:     38672,
+		FsSize:      233681,
+		FsFree:      166413,
+		Temperature: 31.5,
+	}
+}
+
+// updateSettings applies the query parameters of a request to /settings or /settings/<component> using the
+// paths of the known settings, unknown parameters are stored on the top level of the component
+func (s *simulatedPlug) updateSettings(component string, q url.Values) {
+	for param := range q {
+		path := param
+		if component != "" {
+			path = "relays.0." + param
+		}
+
+		for _, setting := range gen1Settings {
+			if setting.Component == component && setting.Param == param {
+				path = setting.Read
+				break
+			}
+		}
+
+		setPath(s.settings, path, simulatedValue(q.Get(param)))
+	}
+}
+
+func (s *simulatedPlug) updateAction(q url.Values) {
+	name := q.Get("name")
+	if _, ok := s.actions[name]; !ok {
+		return
+	}
+
+	s.actions[name] = []shellyctl.Gen1Action{{Index: 0, Enabled: q.Get("enabled") == "true", URLs: []string{q.Get("urls[]")}}}
+}
+
+// simulatedValue converts a query parameter to the JSON type the device would report
+func simulatedValue(v string) any {
+	switch v {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+
+	if f, err := strconv.ParseFloat(v, 64); err == nil {
+		return f
+	}
+
+	return v
+}
+
+// setPath sets a value in nested maps and lists using a dotted path like relays.0.name, missing maps are created
+func setPath(data any, path string, value any) {
+	parts := strings.Split(path, ".")
+
+	for i, part := range parts {
+		last := i == len(parts)-1
+
+		switch d := data.(type) {
+		case map[string]any:
+			if last {
+				d[part] = value
+				return
+			}
+
+			if _, ok := d[part]; !ok {
+				d[part] = map[string]any{}
+			}
+			data = d[part]
+
+		case []any:
+			idx, err := strconv.Atoi(part)
+			if err != nil || idx < 0 || idx >= len(d) {
+				return
+			}
+
+			if last {
+				d[idx] = value
+				return
+			}
+			data = d[idx]
+
+		default:
+			return
+		}
+	}
+}
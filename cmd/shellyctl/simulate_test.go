@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/ripienaar/shellyctl"
+)
+
+func TestSimulatedPlug(t *testing.T) {
+	srv := httptest.NewServer(newSimulatedPlug())
+	defer srv.Close()
+
+	plug, err := shellyctl.NewPlug(url.URL{Scheme: "http", Host: srv.Listener.Addr().String()})
+	if err != nil {
+		t.Fatalf("could not create plug: %v", err)
+	}
+
+	ctx := context.Background()
+
+	_, err = plug.TurnOn(ctx)
+	if err != nil {
+		t.Fatalf("turning on failed: %v", err)
+	}
+
+	status, err := plug.Status(ctx)
+	if err != nil {
+		t.Fatalf("status failed: %v", err)
+	}
+	if !status.Relays[0].IsOn || status.Meters[0].Power != simulatedPower {
+		t.Fatalf("expected the relay to be on and using power: %+v %+v", status.Relays[0], status.Meters[0])
+	}
+
+	_, err = plug.UpdateSettings(ctx, "relay/0", map[string]string{"auto_off": "60"})
+	if err != nil {
+		t.Fatalf("updating settings failed: %v", err)
+	}
+
+	settings, err := plug.Settings(ctx)
+	if err != nil {
+		t.Fatalf("settings failed: %v", err)
+	}
+	if v, _ := lookupPath(settings, "relays.0.auto_off"); v != 60.0 {
+		t.Fatalf("expected auto_off to be 60 got %v", v)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+	"sync"
+)
+
+var (
+	unixSocket string
+
+	// latestReadings holds the most recent energy reading of every device for the control socket
+	latestReadings = &readingStore{readings: map[string]map[string]any{}}
+)
+
+type readingStore struct {
+	readings map[string]map[string]any
+	mu       sync.Mutex
+}
+
+func (s *readingStore) set(address string, reading map[string]any) {
+	s.mu.Lock()
+	s.readings[address] = reading
+	s.mu.Unlock()
+}
+
+func (s *readingStore) json() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return json.Marshal(s.readings)
+}
+
+// serveUnixSocket listens on path for control commands, GET returns the latest readings keyed by
+// device address and STOP stops the command like an interrupt does, the socket is removed on exit
+func serveUnixSocket(path string) error {
+	// a socket left behind by a process that did not exit cleanly can be removed, a live one cannot
+	conn, err := net.Dial("unix", path)
+	if err == nil {
+		conn.Close()
+		return fmt.Errorf("%s is in use by another process", path)
+	}
+	os.Remove(path)
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+
+	// only the user running shellyctl may read readings or stop it
+	err = os.Chmod(path, 0600)
+	if err != nil {
+		listener.Close()
+		return err
+	}
+
+	atExit(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go handleSocketConn(conn)
+		}
+	}()
+
+	return nil
+}
+
+func handleSocketConn(conn net.Conn) {
+	defer conn.Close()
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		switch strings.ToUpper(strings.TrimSpace(scanner.Text())) {
+		case "GET":
+			j, err := latestReadings.json()
+			if err != nil {
+				fmt.Fprintf(conn, "ERROR %v\n", err)
+				continue
+			}
+			fmt.Fprintln(conn, string(j))
+
+		case "STOP":
+			slog.Info("Stopping on request from control socket")
+			fmt.Fprintln(conn, "OK")
+			stopCommand()
+			return
+
+		case "":
+
+		default:
+			fmt.Fprintln(conn, "ERROR unknown command, valid commands are GET and STOP")
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUnixSocket(t *testing.T) {
+	prevCtx, prevStop := ctx, stopCommand
+	t.Cleanup(func() {
+		runExitHooks()
+		ctx, stopCommand = prevCtx, prevStop
+	})
+	ctx, stopCommand = context.WithCancel(context.Background())
+
+	// unix socket paths are limited in length
+	dir, err := os.MkdirTemp("", "shellyctl")
+	if err != nil {
+		t.Fatalf("could not create directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "control.sock")
+
+	err = serveUnixSocket(path)
+	if err != nil {
+		t.Fatalf("could not serve socket: %v", err)
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("could not stat socket: %v", err)
+	}
+	if mode := stat.Mode().Perm(); mode != 0600 {
+		t.Fatalf("expected the socket to be private got %v", mode)
+	}
+
+	latestReadings.set("192.168.1.10", map[string]any{"power_watt": 2.5})
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("could not connect: %v", err)
+	}
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	fmt.Fprintln(conn, "GET")
+	line, _ := reader.ReadString('\n')
+	if line != `{"192.168.1.10":{"power_watt":2.5}}`+"\n" {
+		t.Fatalf("unexpected readings %q", line)
+	}
+
+	fmt.Fprintln(conn, "STOP")
+	line, _ = reader.ReadString('\n')
+	if line != "OK\n" {
+		t.Fatalf("unexpected response %q", line)
+	}
+
+	<-ctx.Done()
+}
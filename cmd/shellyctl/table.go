@@ -0,0 +1,91 @@
+package main
+
+import (
+	"io"
+	"regexp"
+	"sync"
+
+	"github.com/mattn/go-isatty"
+	"github.com/olekukonko/tablewriter"
+	"golang.org/x/term"
+)
+
+var outputFormat string
+
+// tableFormat determines if output should be rendered as tables, tables are only used when writing to a
+// terminal, otherwise the text format is used
+func tableFormat() bool {
+	return outputFormat == "table" && isatty.IsTerminal(output.Fd())
+}
+
+// renderTable writes rows as a table, when the table is wider than the terminal cells are wrapped to fit
+func renderTable(w io.Writer, header []string, rows [][]string) {
+	table := tablewriter.NewWriter(w)
+	table.SetHeader(header)
+	table.SetAutoFormatHeaders(false)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAutoWrapText(false)
+
+	width, _, err := term.GetSize(int(output.Fd()))
+	if err == nil && width > 0 && tableWidth(header, rows) > width {
+		// wrapping does not account for colors so they are removed from wrapped tables
+		for _, row := range rows {
+			for i, cell := range row {
+				row[i] = ansiEscapes.ReplaceAllString(cell, "")
+			}
+		}
+
+		// allow for the borders and padding around every column
+		table.SetAutoWrapText(true)
+		table.SetColWidth(max((width-3*len(header)-1)/len(header), 10))
+	}
+
+	table.AppendBulk(rows)
+	table.Render()
+}
+
+var ansiEscapes = regexp.MustCompile("\033\\[[0-9;]*m")
+
+// tableWidth calculates the width of the table before any wrapping is done
+func tableWidth(header []string, rows [][]string) int {
+	widths := make([]int, len(header))
+	for i, h := range header {
+		widths[i] = tablewriter.DisplayWidth(h)
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			if i < len(widths) {
+				widths[i] = max(widths[i], tablewriter.DisplayWidth(cell))
+			}
+		}
+	}
+
+	total := 1
+	for _, w := range widths {
+		total += w + 3
+	}
+
+	return total
+}
+
+// tableHistory holds table rows per device so tables can grow on every refresh in watch mode
+type tableHistory struct {
+	rows map[string][][]string
+	mu   sync.Mutex
+}
+
+var energyReadings = &tableHistory{rows: map[string][][]string{}}
+
+// add records row for address and returns all rows recorded for it, previous rows are only kept in watch mode
+func (h *tableHistory) add(address string, row []string) [][]string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !watchMode {
+		return [][]string{row}
+	}
+
+	h.rows[address] = append(h.rows[address], row)
+
+	return h.rows[address]
+}
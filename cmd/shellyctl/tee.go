@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	teeJSON       string
+	teePrometheus string
+	teeInflux     string
+
+	// tees receives every energy reading in addition to the normal output when any --tee flag is set
+	tees *teeSinks
+
+	promLabelEscaper  = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	influxTagEscaper  = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+	teeReadingMetrics = []struct{ reading, prometheus, influx string }{
+		{"power_watt", "shelly_power_watts", "power_watt"},
+		{"power_total_kwh", "shelly_energy_kwh", "power_total_kwh"},
+		{"is_on", "shelly_relay_on", "relay_on"},
+	}
+)
+
+// teeSinks writes energy readings to files in JSON, Prometheus text and InfluxDB line protocol formats, JSON and
+// InfluxDB readings are appended while the Prometheus file is replaced with the latest reading of every device
+type teeSinks struct {
+	json       *os.File
+	influx     *os.File
+	prometheus string
+	latest     map[string]teeReading
+
+	mu sync.Mutex
+}
+
+// teeReading is the latest reading of a device and its labels
+type teeReading struct {
+	labels  map[string]string
+	reading map[string]any
+}
+
+// openTeeSinks opens the files set using --tee-json, --tee-prometheus and --tee-influx, nil is returned when
+// none are set
+func openTeeSinks() (*teeSinks, error) {
+	if teeJSON == "" && teePrometheus == "" && teeInflux == "" {
+		return nil, nil
+	}
+
+	t := &teeSinks{prometheus: teePrometheus, latest: map[string]teeReading{}}
+
+	var err error
+	if teeJSON != "" {
+		t.json, err = os.OpenFile(teeJSON, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if teeInflux != "" {
+		t.influx, err = os.OpenFile(teeInflux, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			t.close()
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+func (t *teeSinks) close() {
+	if t.json != nil {
+		t.json.Close()
+	}
+	if t.influx != nil {
+		t.influx.Close()
+	}
+}
+
+// write sends the reading of the device at address taken at ts to every sink
+func (t *teeSinks) write(address string, labels map[string]string, reading map[string]any, ts time.Time) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var errs []error
+
+	if t.json != nil {
+		entry := map[string]any{"time": ts.Format(time.RFC3339), "address": address}
+		if len(labels) > 0 {
+			entry["labels"] = labels
+		}
+		for k, v := range reading {
+			entry[k] = v
+		}
+
+		j, err := json.Marshal(entry)
+		if err == nil {
+			_, err = fmt.Fprintln(t.json, string(j))
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("could not write %s: %v", t.json.Name(), err))
+		}
+	}
+
+	if t.influx != nil {
+		_, err := t.influx.WriteString(influxLine(address, labels, reading, ts))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("could not write %s: %v", t.influx.Name(), err))
+		}
+	}
+
+	if t.prometheus != "" {
+		t.latest[address] = teeReading{labels: labels, reading: reading}
+
+		err := writeFileAtomic(t.prometheus, prometheusText(t.latest))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("could not write %s: %v", t.prometheus, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// prometheusText formats the readings keyed by device address in the Prometheus text format as used by the
+// node_exporter textfile collector
+func prometheusText(readings map[string]teeReading) []byte {
+	var buf bytes.Buffer
+
+	for _, metric := range teeReadingMetrics {
+		fmt.Fprintf(&buf, "# TYPE %s gauge\n", metric.prometheus)
+
+		for _, address := range sortedKeys(readings) {
+			value, ok := readings[address].reading[metric.reading]
+			if !ok {
+				continue
+			}
+
+			labels := readings[address].labels
+			pairs := []string{fmt.Sprintf(`address="%s"`, promLabelEscaper.Replace(address))}
+			for _, k := range sortedKeys(labels) {
+				pairs = append(pairs, fmt.Sprintf(`%s="%s"`, k, promLabelEscaper.Replace(labels[k])))
+			}
+
+			fmt.Fprintf(&buf, "%s{%s} %v\n", metric.prometheus, strings.Join(pairs, ","), value)
+		}
+	}
+
+	return buf.Bytes()
+}
+
+// influxLine formats a reading using the InfluxDB line protocol, labels are added as tags
+func influxLine(address string, labels map[string]string, reading map[string]any, ts time.Time) string {
+	tags := []string{"shelly", "address=" + influxTagEscaper.Replace(address)}
+	for _, k := range sortedKeys(labels) {
+		tags = append(tags, influxTagEscaper.Replace(k)+"="+influxTagEscaper.Replace(labels[k]))
+	}
+
+	var fields []string
+	for _, metric := range teeReadingMetrics {
+		if value, ok := reading[metric.reading]; ok {
+			fields = append(fields, fmt.Sprintf("%s=%v", metric.influx, value))
+		}
+	}
+
+	return fmt.Sprintf("%s %s %d\n", strings.Join(tags, ","), strings.Join(fields, ","), ts.UnixNano())
+}
+
+// writeFileAtomic replaces path with data, readers never see a partially written file
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+
+	err = tmp.Chmod(0644)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTeeSinks(t *testing.T) {
+	dir := t.TempDir()
+	teeJSON = filepath.Join(dir, "energy.json")
+	teePrometheus = filepath.Join(dir, "shelly.prom")
+	teeInflux = filepath.Join(dir, "energy.influx")
+	t.Cleanup(func() { teeJSON, teePrometheus, teeInflux = "", "", "" })
+
+	sinks, err := openTeeSinks()
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	defer sinks.close()
+
+	ts := time.Unix(1700000000, 0)
+	for _, address := range []string{"192.168.1.10", "192.168.1.11"} {
+		err = sinks.write(address, map[string]string{"room": "living room"}, map[string]any{"power_watt": 2.5, "power_total_kwh": 0.75, "is_on": 1.0}, ts)
+		if err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+
+	j, _ := os.ReadFile(teeJSON)
+	if lines := strings.Split(strings.TrimSpace(string(j)), "\n"); len(lines) != 2 || !strings.Contains(lines[1], `"address":"192.168.1.11"`) {
+		t.Fatalf("unexpected JSON output:\n%s", j)
+	}
+
+	influx, _ := os.ReadFile(teeInflux)
+	expected := "shelly,address=192.168.1.10,room=living\\ room power_watt=2.5,power_total_kwh=0.75,relay_on=1 1700000000000000000\n"
+	if !strings.HasPrefix(string(influx), expected) {
+		t.Fatalf("unexpected influx output:\n%s", influx)
+	}
+
+	prom, _ := os.ReadFile(teePrometheus)
+	for _, line := range []string{
+		"# TYPE shelly_power_watts gauge",
+		`shelly_power_watts{address="192.168.1.10",room="living room"} 2.5`,
+		"# TYPE shelly_energy_kwh gauge",
+		`shelly_energy_kwh{address="192.168.1.10",room="living room"} 0.75`,
+		`shelly_relay_on{address="192.168.1.11",room="living room"} 1`,
+	} {
+		if !strings.Contains(string(prom), line+"\n") {
+			t.Fatalf("prometheus output does not contain %q:\n%s", line, prom)
+		}
+	}
+}
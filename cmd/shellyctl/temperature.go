@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/choria-io/fisk"
+	"github.com/ripienaar/shellyctl"
+)
+
+// requireSensor ensures that plug is a temperature sensor or thermostat
+func requireSensor(ctx context.Context, plug shellyctl.Plug) (shellyctl.Sensor, error) {
+	sensor, ok := plug.(shellyctl.Sensor)
+	if ok {
+		return sensor, nil
+	}
+
+	nfo, err := plug.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, fmt.Errorf("this command requires a Shelly H&T or TRV, %s is not a sensor", nfo.Type)
+}
+
+func temperatureAction(_ *fisk.ParseContext) error {
+	return forEachDevice(temperatureDevice)
+}
+
+func temperatureDevice(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+	sensor, err := requireSensor(ctx, plug)
+	if err != nil {
+		return err
+	}
+
+	status, err := sensor.SensorStatus(ctx)
+	if err != nil {
+		return err
+	}
+
+	if jsonFormat {
+		return writeJSON(w, status)
+	}
+
+	fmt.Fprintf(w, "Sensor information for %s\n", address)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "         Temperature: %.1f °C\n", status.Temperature)
+	if status.Target != nil {
+		fmt.Fprintf(w, "  Target Temperature: %.1f °C\n", *status.Target)
+	}
+	if status.Humidity != nil {
+		fmt.Fprintf(w, "            Humidity: %.1f %%\n", *status.Humidity)
+	}
+	fmt.Fprintf(w, "             Battery: %d %%\n", status.Battery)
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/ripienaar/shellyctl"
+)
+
+var outputTemplate string
+
+// templateData is the data available to templates set using --output-template, also used for info JSON output
+type templateData struct {
+	Address string                  `json:"address"`
+	Info    *shellyctl.DeviceInfo   `json:"info"`
+	Status  *shellyctl.DeviceStatus `json:"status"`
+}
+
+// parseOutputTemplate parses the template set using --output-template, templates starting with @ are read from a file
+func parseOutputTemplate() (*template.Template, error) {
+	body := outputTemplate
+	if strings.HasPrefix(body, "@") {
+		tb, err := os.ReadFile(strings.TrimPrefix(body, "@"))
+		if err != nil {
+			return nil, err
+		}
+		body = string(tb)
+	}
+
+	funcs := template.FuncMap{
+		"json": func(v any) (string, error) {
+			j, err := json.Marshal(v)
+			return string(j), err
+		},
+	}
+
+	tmpl, err := template.New("output").Funcs(funcs).Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid output template: %v", err)
+	}
+
+	return tmpl, nil
+}
+
+func renderOutputTemplate(w io.Writer, address string, nfo *shellyctl.DeviceInfo, status *shellyctl.DeviceStatus) error {
+	tmpl, err := parseOutputTemplate()
+	if err != nil {
+		return err
+	}
+
+	err = tmpl.Execute(w, templateData{Address: address, Info: nfo, Status: status})
+	if err != nil {
+		return err
+	}
+
+	if !strings.HasSuffix(outputTemplate, "\n") && !strings.HasPrefix(outputTemplate, "@") {
+		fmt.Fprintln(w)
+	}
+
+	return nil
+}
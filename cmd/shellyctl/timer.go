@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/ripienaar/shellyctl"
+)
+
+func timerStatusAction(_ *fisk.ParseContext) error {
+	return forEachDevice(timerStatusDevice)
+}
+
+func timerStatusDevice(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+	_, err := requireGen1(ctx, plug)
+	if err != nil {
+		return err
+	}
+
+	relay, err := plug.RelayStatus(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !relay.HasTimer {
+		fmt.Fprintf(w, "No timer is active on %s\n", address)
+		return nil
+	}
+
+	fmt.Fprintf(w, "Timer information for %s\n", address)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "        Power Status: %s\n", onOffString(relay.IsOn))
+	fmt.Fprintf(w, "             Started: %v\n", time.Unix(relay.TimerStarted, 0).In(displayLocation))
+	fmt.Fprintf(w, "            Duration: %v\n", time.Duration(relay.TimerDuration)*time.Second)
+	fmt.Fprintf(w, "           Remaining: %v\n", time.Duration(relay.TimerRemaining)*time.Second)
+
+	return nil
+}
+
+func timerCancelAction(_ *fisk.ParseContext) error {
+	return forEachDevice(timerCancelDevice)
+}
+
+func timerCancelDevice(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+	_, err := requireGen1(ctx, plug)
+	if err != nil {
+		return err
+	}
+
+	relay, err := plug.RelayStatus(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !relay.HasTimer {
+		return fmt.Errorf("no timer is active")
+	}
+
+	_, err = plug.CancelTimer(ctx)
+	if err != nil {
+		return fmt.Errorf("could not cancel timer: %v", err)
+	}
+
+	fmt.Fprintf(w, "Timer cancelled and device %s turned off\n", address)
+
+	return nil
+}
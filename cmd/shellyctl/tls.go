@@ -0,0 +1,131 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/choria-io/fisk"
+)
+
+var (
+	useHTTPS          bool
+	skipHostnameCheck bool
+	sslMinVersion     string
+	caCert            string
+
+	// verifySSL is disabled using --no-verify-ssl
+	verifySSL = true
+
+	tlsVersions = map[string]uint16{"TLS1.2": tls.VersionTLS12, "TLS1.3": tls.VersionTLS13}
+
+	// rootCAs holds the system roots and the --ca-cert certificate, nil uses the system roots
+	rootCAs *x509.CertPool
+)
+
+// configureCACert adds the certificate given using --ca-cert to the system roots
+func configureCACert(_ *fisk.ParseContext) error {
+	if caCert == "" {
+		return nil
+	}
+
+	pool, err := loadCACert(caCert)
+	if err != nil {
+		return err
+	}
+
+	rootCAs = pool
+
+	return nil
+}
+
+// loadCACert creates a pool holding the system roots and the PEM encoded certificates in file
+func loadCACert(file string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read CA certificate: %v", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("%s does not contain a PEM encoded certificate", file)
+	}
+
+	return pool, nil
+}
+
+// tlsConfig creates the TLS configuration used when connecting to the device at address over HTTPS, fingerprints
+// are those recorded using pin-fingerprint or on first use. Verification is disabled using --no-verify-ssl
+func tlsConfig(address string, fingerprints map[string]string) (*tls.Config, error) {
+	if !useHTTPS {
+		return nil, nil
+	}
+
+	minVersion, ok := tlsVersions[sslMinVersion]
+	if !ok {
+		minVersion = tls.VersionTLS12
+	}
+
+	cfg := &tls.Config{MinVersion: minVersion, RootCAs: rootCAs}
+
+	fingerprint := pinnedFingerprint
+	if fingerprint == "" {
+		fingerprint = fingerprints[address]
+	}
+
+	switch {
+	case !verifySSL:
+		cfg.InsecureSkipVerify = true
+
+	case fingerprint != "":
+		// a pinned certificate is trusted regardless of who issued it
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyPeerCertificate = verifyFingerprint(fingerprint)
+
+	case skipHostnameCheck:
+		// the standard verification is disabled and replaced by one that validates the chain but not the hostname
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyPeerCertificate = verifyChainOnly(cfg)
+
+	case trustOnFirstUse:
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyPeerCertificate = verifyOrPin(address, cfg)
+	}
+
+	return cfg, nil
+}
+
+// verifyChainOnly verifies that the certificate chain presented by the device is trusted without checking the hostname
+func verifyChainOnly(cfg *tls.Config) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return fmt.Errorf("no certificates presented by the device")
+		}
+
+		var certs []*x509.Certificate
+		for _, raw := range rawCerts {
+			cert, err := x509.ParseCertificate(raw)
+			if err != nil {
+				return fmt.Errorf("invalid certificate presented by the device: %v", err)
+			}
+			certs = append(certs, cert)
+		}
+
+		opts := x509.VerifyOptions{
+			Roots:         cfg.RootCAs,
+			Intermediates: x509.NewCertPool(),
+		}
+		for _, cert := range certs[1:] {
+			opts.Intermediates.AddCert(cert)
+		}
+
+		_, err := certs[0].Verify(opts)
+
+		return err
+	}
+}
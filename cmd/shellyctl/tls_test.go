@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ripienaar/shellyctl"
+)
+
+func TestTLSConfig(t *testing.T) {
+	t.Cleanup(func() { useHTTPS, verifySSL, sslMinVersion = false, true, "" })
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"type":"SHPLG-S"}`))
+	}))
+	defer srv.Close()
+
+	address := srv.Listener.Addr().String()
+	useHTTPS, sslMinVersion = true, "TLS1.3"
+
+	info := func() error {
+		cfg, err := tlsConfig(address, nil)
+		if err != nil {
+			t.Fatalf("tls config failed: %v", err)
+		}
+		if cfg.MinVersion != tls.VersionTLS13 {
+			t.Fatalf("expected TLS 1.3 to be the minimum version")
+		}
+
+		plug, err := shellyctl.NewPlug(url.URL{Scheme: "https", Host: address}, shellyctl.WithTLSConfig(cfg))
+		if err != nil {
+			t.Fatalf("could not create plug: %v", err)
+		}
+		_, err = plug.Info(context.Background())
+		return err
+	}
+
+	err := info()
+	if !errors.Is(err, shellyctl.ErrCertificateInvalid) || !strings.Contains(err.Error(), "unknown certificate authority") {
+		t.Fatalf("expected the self-signed certificate to be rejected with its reason: %v", err)
+	}
+
+	verifySSL = false
+	err = info()
+	if err != nil {
+		t.Fatalf("request without verification failed: %v", err)
+	}
+}
+
+func TestTrustOnFirstUse(t *testing.T) {
+	t.Cleanup(func() { useHTTPS, trustOnFirstUse = false, false })
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"type":"SHPLG-S"}`))
+	}))
+	defer srv.Close()
+
+	address := srv.Listener.Addr().String()
+	useHTTPS = true
+
+	info := func() error {
+		fingerprints, err := loadFingerprints()
+		if err != nil {
+			t.Fatalf("could not load fingerprints: %v", err)
+		}
+
+		cfg, err := tlsConfig(address, fingerprints)
+		if err != nil {
+			t.Fatalf("tls config failed: %v", err)
+		}
+
+		plug, err := shellyctl.NewPlug(url.URL{Scheme: "https", Host: address}, shellyctl.WithTLSConfig(cfg))
+		if err != nil {
+			t.Fatalf("could not create plug: %v", err)
+		}
+		_, err = plug.Info(context.Background())
+		return err
+	}
+
+	// untrusted certificates are rejected and not recorded unless --trust-on-first-use is set
+	err := info()
+	if !errors.Is(err, shellyctl.ErrCertificateInvalid) {
+		t.Fatalf("expected the untrusted certificate to be rejected by default: %v", err)
+	}
+	if fingerprints, _ := loadFingerprints(); len(fingerprints) != 0 {
+		t.Fatalf("expected no fingerprint to be recorded: %v", fingerprints)
+	}
+
+	trustOnFirstUse = true
+	err = info()
+	if err != nil {
+		t.Fatalf("first use was not trusted: %v", err)
+	}
+
+	fingerprints, _ := loadFingerprints()
+	if fingerprints[address] != certFingerprint(srv.Certificate().Raw) {
+		t.Fatalf("fingerprint was not recorded: %v", fingerprints)
+	}
+
+	err = info()
+	if err != nil {
+		t.Fatalf("pinned certificate was not trusted: %v", err)
+	}
+
+	// a certificate that does not match the recorded one is rejected and not pinned again
+	err = saveFingerprint(address, "SHA256:00")
+	if err != nil {
+		t.Fatalf("could not save fingerprint: %v", err)
+	}
+	err = info()
+	if err == nil || !strings.Contains(err.Error(), "does not match pinned fingerprint") {
+		t.Fatalf("expected a changed certificate to be rejected: %v", err)
+	}
+}
+
+func TestPinFingerprintDevice(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	address := srv.Listener.Addr().String()
+
+	var out strings.Builder
+	err := pinFingerprintDevice(context.Background(), address, nil, &out)
+	if err != nil {
+		t.Fatalf("pinning failed: %v", err)
+	}
+
+	fingerprints, _ := loadFingerprints()
+	if fingerprints[address] != certFingerprint(srv.Certificate().Raw) {
+		t.Fatalf("fingerprint was not recorded: %v", fingerprints)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/choria-io/fisk"
+)
+
+// these are set at build time using -ldflags "-X main.version=v1.2.3 -X main.commit=... -X main.buildDate=..."
+var (
+	version   = "development"
+	commit    = "unknown"
+	buildDate = "unknown"
+)
+
+type versionInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+}
+
+func versionAction(_ *fisk.ParseContext) error {
+	nfo := versionInfo{
+		Version:   version,
+		Commit:    commit,
+		BuildDate: buildDate,
+		GoVersion: runtime.Version(),
+	}
+
+	if jsonFormat {
+		return writeJSON(output, nfo)
+	}
+
+	fmt.Fprintf(output, "             Version: %s\n", nfo.Version)
+	fmt.Fprintf(output, "              Commit: %s\n", nfo.Commit)
+	fmt.Fprintf(output, "          Build Date: %s\n", nfo.BuildDate)
+	fmt.Fprintf(output, "          Go Version: %s\n", nfo.GoVersion)
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/mattn/go-isatty"
+	"github.com/ripienaar/shellyctl"
+)
+
+var (
+	watchMode       bool
+	watchInterval   time.Duration
+	watchTimestamps bool
+)
+
+// watchLoop calls fn every interval, when the output is a terminal the screen is cleared before every call
+// so the output refreshes in place, errors are logged and the loop continues until interrupted
+func watchLoop(interval time.Duration, fn func() error) error {
+	tty := isatty.IsTerminal(output.Fd())
+
+	for i := 0; ; i++ {
+		switch {
+		case tty:
+			fmt.Fprint(output, "\033[H\033[2J")
+		case i > 0:
+			fmt.Fprintln(output)
+		}
+
+		err := fn()
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			slog.Warn("Updating failed", "error", err)
+		}
+
+		if sleep(ctx, interval) != nil {
+			return nil
+		}
+	}
+}
+
+// sleep waits for d to pass, returns early with the context error when ctx is cancelled
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// timestamped adds timestamps to the output of action when --timestamps is set in watch mode, JSON documents get a
+// timestamp field from writeJSON and every other line, including values selected using --output-jq and
+// --json-path, is prefixed with the time
+func timestamped(action deviceAction) deviceAction {
+	if !watchMode || !watchTimestamps {
+		return action
+	}
+
+	if (jsonFormat || choriaFormat) && outputJQ == "" && outputJSONPath == "" {
+		return action
+	}
+
+	return func(ctx context.Context, address string, plug shellyctl.Plug, w io.Writer) error {
+		return action(ctx, address, plug, &timestampWriter{w: w, lineStart: true})
+	}
+}
+
+// withTimestamp adds a timestamp field to v when --timestamps is set in watch mode, values that are not JSON
+// objects are returned unchanged
+func withTimestamp(v any) any {
+	if !watchMode || !watchTimestamps {
+		return v
+	}
+
+	j, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+
+	var res map[string]any
+	err = json.Unmarshal(j, &res)
+	if err != nil {
+		return v
+	}
+
+	res["timestamp"] = time.Now().Format(time.RFC3339)
+
+	return res
+}
+
+// timestampWriter prefixes every line that is not empty with the current time
+type timestampWriter struct {
+	w         io.Writer
+	lineStart bool
+}
+
+func (t *timestampWriter) Write(p []byte) (int, error) {
+	var buf bytes.Buffer
+
+	for _, line := range bytes.SplitAfter(p, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+
+		if t.lineStart && line[0] != '\n' {
+			buf.WriteString(time.Now().Format(time.RFC3339) + " ")
+		}
+		buf.Write(line)
+
+		t.lineStart = line[len(line)-1] == '\n'
+	}
+
+	_, err := t.w.Write(buf.Bytes())
+	if err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
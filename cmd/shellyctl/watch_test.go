@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"testing"
+
+	"github.com/ripienaar/shellyctl"
+)
+
+func TestTimestamped(t *testing.T) {
+	resetOutputFlags(t)
+	t.Cleanup(func() { watchMode, watchTimestamps = false, false })
+
+	action := func(_ context.Context, _ string, _ shellyctl.Plug, w io.Writer) error {
+		fmt.Fprint(w, "Meter Information\n\n")
+		fmt.Fprint(w, "               Power: ")
+		fmt.Fprintln(w, "2.43 Watt")
+		return nil
+	}
+
+	run := func() string {
+		var out bytes.Buffer
+		timestamped(action)(context.Background(), "", nil, &out)
+		return out.String()
+	}
+
+	watchTimestamps = true
+	if out := run(); out != "Meter Information\n\n               Power: 2.43 Watt\n" {
+		t.Fatalf("timestamps were added outside of watch mode:\n%s", out)
+	}
+
+	watchMode = true
+	expected := regexp.MustCompile(`^\d{4}-\d\d-\d\dT\S+ Meter Information\n\n\d{4}-\d\d-\d\dT\S+                Power: 2.43 Watt\n$`)
+	if out := run(); !expected.MatchString(out) {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+
+	jsonFormat = true
+	var out bytes.Buffer
+	err := writeJSON(&out, map[string]any{"power_watt": 2.43})
+	if err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if !regexp.MustCompile(`"timestamp": "\d{4}-`).Match(out.Bytes()) {
+		t.Fatalf("expected a timestamp field:\n%s", out.String())
+	}
+}
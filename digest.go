@@ -0,0 +1,155 @@
+package shellyctl
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// digestHashes are the Digest algorithms supported, Gen 2 devices use SHA-256
+var digestHashes = map[string]func() hash.Hash{
+	"MD5":     md5.New,
+	"SHA-256": sha256.New,
+}
+
+// digestTransport retries requests rejected with a Digest challenge using HTTP Digest authentication as required by
+// Gen 2 devices with authentication enabled, Gen 1 devices accept the Basic credentials sent with every request
+type digestTransport struct {
+	user      string
+	pass      string
+	transport http.RoundTripper
+
+	nc atomic.Uint32
+}
+
+func (d *digestTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := d.transport.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	challenge, ok := parseDigestChallenge(resp.Header.Get("WWW-Authenticate"))
+	if !ok {
+		return resp, nil
+	}
+
+	retry := req.Clone(req.Context())
+	if req.Body != nil && req.Body != http.NoBody {
+		// the body was consumed by the first attempt
+		if req.GetBody == nil {
+			return resp, nil
+		}
+
+		retry.Body, err = req.GetBody()
+		if err != nil {
+			return resp, nil
+		}
+	}
+
+	auth, err := d.authorization(retry, challenge)
+	if err != nil {
+		// an unsupported challenge is reported as the authentication failure it is
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	retry.Header.Set("Authorization", auth)
+
+	return d.transport.RoundTrip(retry)
+}
+
+// authorization calculates the Authorization header answering challenge for req as described in RFC 7616
+func (d *digestTransport) authorization(req *http.Request, challenge map[string]string) (string, error) {
+	algorithm := challenge["algorithm"]
+	if algorithm == "" {
+		algorithm = "MD5"
+	}
+
+	newHash, ok := digestHashes[strings.ToUpper(algorithm)]
+	if !ok {
+		return "", fmt.Errorf("unsupported digest algorithm %s", algorithm)
+	}
+
+	h := func(parts ...string) string {
+		hf := newHash()
+		hf.Write([]byte(strings.Join(parts, ":")))
+		return hex.EncodeToString(hf.Sum(nil))
+	}
+
+	uri := req.URL.RequestURI()
+	ha1 := h(d.user, challenge["realm"], d.pass)
+	ha2 := h(req.Method, uri)
+
+	fields := []string{
+		fmt.Sprintf("username=%q", d.user),
+		fmt.Sprintf("realm=%q", challenge["realm"]),
+		fmt.Sprintf("nonce=%q", challenge["nonce"]),
+		fmt.Sprintf("uri=%q", uri),
+		"algorithm=" + algorithm,
+	}
+
+	qopAuth := false
+	for _, qop := range strings.Split(challenge["qop"], ",") {
+		qopAuth = qopAuth || strings.TrimSpace(qop) == "auth"
+	}
+
+	if qopAuth {
+		cb := make([]byte, 8)
+		_, err := rand.Read(cb)
+		if err != nil {
+			return "", err
+		}
+		cnonce := hex.EncodeToString(cb)
+		nc := fmt.Sprintf("%08x", d.nc.Add(1))
+
+		fields = append(fields,
+			fmt.Sprintf("response=%q", h(ha1, challenge["nonce"], nc, cnonce, "auth", ha2)),
+			"qop=auth",
+			"nc="+nc,
+			fmt.Sprintf("cnonce=%q", cnonce),
+		)
+	} else {
+		fields = append(fields, fmt.Sprintf("response=%q", h(ha1, challenge["nonce"], ha2)))
+	}
+
+	if opaque, ok := challenge["opaque"]; ok {
+		fields = append(fields, fmt.Sprintf("opaque=%q", opaque))
+	}
+
+	return "Digest " + strings.Join(fields, ", "), nil
+}
+
+// parseDigestChallenge parses the parameters of a Digest WWW-Authenticate header, quoted values may hold commas
+func parseDigestChallenge(header string) (map[string]string, bool) {
+	scheme, params, _ := strings.Cut(strings.TrimSpace(header), " ")
+	if !strings.EqualFold(scheme, "Digest") {
+		return nil, false
+	}
+
+	res := map[string]string{}
+	for params != "" {
+		var key, value string
+		var ok bool
+
+		key, params, ok = strings.Cut(strings.TrimLeft(params, " ,"), "=")
+		if !ok {
+			break
+		}
+
+		if strings.HasPrefix(params, `"`) {
+			value, params, _ = strings.Cut(params[1:], `"`)
+		} else {
+			value, params, _ = strings.Cut(params, ",")
+		}
+
+		res[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+	}
+
+	return res, res["nonce"] != ""
+}
@@ -0,0 +1,99 @@
+package shellyctl
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseDigestChallenge(t *testing.T) {
+	challenge, ok := parseDigestChallenge(`Digest qop="auth,auth-int", realm="shellyplusplugs-e86beae8c5a0", nonce="66a1b2c3", algorithm=SHA-256`)
+	if !ok {
+		t.Fatalf("challenge was not parsed")
+	}
+
+	expected := map[string]string{"qop": "auth,auth-int", "realm": "shellyplusplugs-e86beae8c5a0", "nonce": "66a1b2c3", "algorithm": "SHA-256"}
+	for k, v := range expected {
+		if challenge[k] != v {
+			t.Fatalf("expected %s to be %q got %q", k, v, challenge[k])
+		}
+	}
+
+	if _, ok := parseDigestChallenge(`Basic realm="shelly"`); ok {
+		t.Fatalf("expected Basic challenges to be ignored")
+	}
+}
+
+// digestDevice requires Digest authentication like a Gen 2 device with authentication enabled
+func digestDevice(user string, pass string) *httptest.Server {
+	realm, nonce := "shellyplusplugs-e86beae8c5a0", "66a1b2c3"
+
+	h := func(parts ...string) string {
+		sum := sha256.Sum256([]byte(strings.Join(parts, ":")))
+		return hex.EncodeToString(sum[:])
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		auth, ok := parseDigestChallenge(r.Header.Get("Authorization"))
+		if ok {
+			ha1 := h(user, realm, pass)
+			ha2 := h(r.Method, auth["uri"])
+			ok = auth["username"] == user && auth["response"] == h(ha1, nonce, auth["nc"], auth["cnonce"], auth["qop"], ha2)
+		}
+
+		if !ok {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Digest qop="auth", realm=%q, nonce=%q, algorithm=SHA-256`, realm, nonce))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if r.Method == http.MethodPost && string(body) != `{"id":0}` {
+			http.Error(w, "body was not sent again", http.StatusBadRequest)
+			return
+		}
+
+		w.Write([]byte(`{"id":"shellyplusplugs-e86beae8c5a0","gen":2}`))
+	}))
+}
+
+func TestDigestAuth(t *testing.T) {
+	srv := digestDevice("admin", "secret")
+	defer srv.Close()
+
+	plug, err := NewPlug(serverURL(srv), WithCredentials("admin", "secret"))
+	if err != nil {
+		t.Fatalf("could not create plug: %v", err)
+	}
+
+	nfo, err := plug.Info(context.Background())
+	if err != nil {
+		t.Fatalf("info failed: %v", err)
+	}
+	if nfo.Gen != 2 {
+		t.Fatalf("unexpected info %+v", nfo)
+	}
+
+	res := map[string]any{}
+	err = plug.RPC(context.Background(), "Switch.GetStatus", map[string]any{"id": 0}, &res)
+	if err != nil {
+		t.Fatalf("rpc failed: %v", err)
+	}
+
+	plug, err = NewPlug(serverURL(srv), WithCredentials("admin", "wrong"))
+	if err != nil {
+		t.Fatalf("could not create plug: %v", err)
+	}
+	_, err = plug.Info(context.Background())
+	if !errors.Is(err, ErrAuthFailed) {
+		t.Fatalf("expected wrong credentials to fail: %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+package shellyctl
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// NewDimmer creates a Dimmer for the Gen 1 Shelly Dimmer at address, credentials are taken from the address
+func NewDimmer(address url.URL, opts ...Option) (Dimmer, error) {
+	plug, err := NewPlug(address, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &shellyDimmerV1{shellyPlug: plug.(*shellyPlug)}, nil
+}
+
+// IsDimmer determines if the device is a Gen 1 Shelly Dimmer
+func (i *DeviceInfo) IsDimmer() bool {
+	return strings.HasPrefix(i.Type, "SHDM-")
+}
+
+// shellyDimmerV1 is a Gen 1 Shelly Dimmer, these are controlled using /light/0 instead of /relay/0
+type shellyDimmerV1 struct {
+	*shellyPlug
+}
+
+func (s *shellyDimmerV1) light(ctx context.Context, queries map[string]string) (*Light, error) {
+	var res Light
+
+	err := s.get(ctx, "light/0", queries, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+func (s *shellyDimmerV1) TurnOn(ctx context.Context) (*Relay, error) {
+	res, err := s.light(ctx, map[string]string{"turn": "on"})
+	if err != nil {
+		return nil, err
+	}
+
+	if !res.IsOn && s.cfg.dryRun == nil {
+		return nil, ErrRelayNotOn
+	}
+
+	slog.Info("Light turned on", "device", s.address.Hostname())
+
+	return res.Relay(), nil
+}
+
+func (s *shellyDimmerV1) TurnOff(ctx context.Context) (*Relay, error) {
+	res, err := s.light(ctx, map[string]string{"turn": "off"})
+	if err != nil {
+		return nil, err
+	}
+
+	if res.IsOn && s.cfg.dryRun == nil {
+		return res.Relay(), fmt.Errorf("light is on")
+	}
+
+	slog.Info("Light turned off", "device", s.address.Hostname())
+
+	return res.Relay(), nil
+}
+
+func (s *shellyDimmerV1) RelayStatus(ctx context.Context) (*Relay, error) {
+	res, err := s.light(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Relay(), nil
+}
+
+func (s *shellyDimmerV1) CancelTimer(ctx context.Context) (*Relay, error) {
+	res, err := s.light(ctx, map[string]string{"turn": "off", "timer": "0"})
+	if err != nil {
+		return nil, err
+	}
+
+	if res.HasTimer {
+		return res.Relay(), fmt.Errorf("timer is still active")
+	}
+
+	slog.Info("Light timer cancelled", "device", s.address.Hostname())
+
+	return res.Relay(), nil
+}
+
+func (s *shellyDimmerV1) TurnWithTimer(ctx context.Context, on bool, timer time.Duration) (*Relay, error) {
+	turn := "off"
+	if on {
+		turn = "on"
+	}
+
+	res, err := s.light(ctx, map[string]string{"turn": turn, "timer": fmt.Sprint(int(timer.Seconds()))})
+	if err != nil {
+		return nil, err
+	}
+
+	if !res.HasTimer && s.cfg.dryRun == nil {
+		return res.Relay(), fmt.Errorf("timer is not active")
+	}
+
+	slog.Info("Light timer started", "device", s.address.Hostname(), "turn", turn, "timer", timer)
+
+	return res.Relay(), nil
+}
+
+func (s *shellyDimmerV1) Light(ctx context.Context) (*Light, error) {
+	return s.light(ctx, nil)
+}
+
+func (s *shellyDimmerV1) SetBrightness(ctx context.Context, brightness int) (*Light, error) {
+	if brightness < 0 || brightness > 100 {
+		return nil, fmt.Errorf("brightness must be between 0 and 100")
+	}
+
+	res, err := s.light(ctx, map[string]string{"brightness": fmt.Sprint(brightness)})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("Light brightness set", "device", s.address.Hostname(), "brightness", brightness)
+
+	return res, nil
+}
@@ -0,0 +1,16 @@
+package shellyctl
+
+import "errors"
+
+var (
+	// ErrRelayNotOn indicates the relay is off after turning it on
+	ErrRelayNotOn = errors.New("relay is not on")
+	// ErrDeviceUnreachable indicates the device could not be contacted
+	ErrDeviceUnreachable = errors.New("device unreachable")
+	// ErrCertificateInvalid indicates the certificate presented by the device could not be verified
+	ErrCertificateInvalid = errors.New("certificate verification failed")
+	// ErrAuthFailed indicates the device rejected the credentials
+	ErrAuthFailed = errors.New("authentication failed")
+	// ErrUnsupported indicates the device does not support the requested operation
+	ErrUnsupported = errors.New("not supported by this device")
+)
@@ -1,10 +1,63 @@
-package main
+package shellyctl
 
+import (
+	"context"
+	"time"
+)
+
+// Plug is a Shelly device, created using NewPlug
 type Plug interface {
-	TurnOn() (*Relay, error)
-	TurnOff() (*Relay, error)
-	Status() (*DeviceStatus, error)
-	Info() (*DeviceInfo, error)
+	TurnOn(ctx context.Context) (*Relay, error)
+	TurnOff(ctx context.Context) (*Relay, error)
+	RelayStatus(ctx context.Context) (*Relay, error)
+	CancelTimer(ctx context.Context) (*Relay, error)
+	// TurnWithTimer switches a Gen 1 relay on or off and flips it back once timer passed
+	TurnWithTimer(ctx context.Context, on bool, timer time.Duration) (*Relay, error)
+	Status(ctx context.Context) (*DeviceStatus, error)
+	Info(ctx context.Context) (*DeviceInfo, error)
+
+	// Settings retrieves all settings from a Gen 1 device
+	Settings(ctx context.Context) (map[string]any, error)
+	// UpdateSettings updates settings of a Gen 1 device, component is the path below /settings like relay/0
+	UpdateSettings(ctx context.Context, component string, settings map[string]string) (map[string]any, error)
+	// Get retrieves path from a Gen 1 device and unmarshals the result into response
+	Get(ctx context.Context, path string, response any) error
+	// RPC calls a method on a Gen 2 device and unmarshals the result into response
+	RPC(ctx context.Context, method string, params any, response any) error
+}
+
+// Dimmer is a Shelly Dimmer, created using NewDimmer
+type Dimmer interface {
+	Plug
+
+	// Light retrieves the state of the light
+	Light(ctx context.Context) (*Light, error)
+	// SetBrightness sets the brightness of the light in percent
+	SetBrightness(ctx context.Context, brightness int) (*Light, error)
+}
+
+// Roller is a Shelly device controlling a roller shutter or cover, created using NewRoller
+type Roller interface {
+	Plug
+
+	// Open starts opening the roller
+	Open(ctx context.Context) (*RollerState, error)
+	// Close starts closing the roller
+	Close(ctx context.Context) (*RollerState, error)
+	// Stop stops the roller
+	Stop(ctx context.Context) (*RollerState, error)
+	// SetPosition moves the roller to position in percent, 100 is fully open, requires a calibrated roller
+	SetPosition(ctx context.Context, position int) (*RollerState, error)
+	// RollerStatus retrieves the state of the roller
+	RollerStatus(ctx context.Context) (*RollerState, error)
+}
+
+// Sensor is a Shelly temperature sensor or thermostat, created using NewSensor
+type Sensor interface {
+	Plug
+
+	// SensorStatus retrieves the current sensor readings
+	SensorStatus(ctx context.Context) (*SensorStatus, error)
 }
 
 // DeviceStatus aggregates all status information for the device. Returned from the /status API
@@ -26,15 +79,40 @@ type DeviceStatus struct {
 	FsSize   int64            `json:"fs_size" yaml:"fs_size"`     // Total amount of the file system in bytes
 	FsFree   int64            `json:"fs_free" yaml:"fs_free"`     // Available amount of the file system in bytes
 
+	Temperature     float64 `json:"temperature" yaml:"temperature"`         // Internal device temperature in °C
+	OverTemperature bool    `json:"overtemperature" yaml:"overtemperature"` // Whether the device is overheating
+
+	Lights  []Light       `json:"lights,omitempty" yaml:"lights,omitempty"`   // Array of light statuses, only set by dimmers
+	Rollers []RollerState `json:"rollers,omitempty" yaml:"rollers,omitempty"` // Array of roller statuses, only set in roller mode
+
+	Tmp         *SensorTemperature `json:"tmp,omitempty" yaml:"tmp,omitempty"`                 // Temperature reading, plugs report their internal temperature
+	Hum         *SensorHumidity    `json:"hum,omitempty" yaml:"hum,omitempty"`                 // Humidity reading, only set by H&T sensors
+	Bat         *Battery           `json:"bat,omitempty" yaml:"bat,omitempty"`                 // Battery state, only set by battery powered devices
+	Thermostats []Thermostat       `json:"thermostats,omitempty" yaml:"thermostats,omitempty"` // Array of thermostat statuses, only set by TRVs
 }
 
 // DeviceInfo is the response from the /shelly API
 type DeviceInfo struct {
-	Type   string `json:"type" yaml:"type"`     // Shelly model identifier
-	MAC    string `json:"mac" yaml:"mac"`       // MAC address of the device
-	Auth   bool   `json:"auth" yaml:"auth"`     // Whether HTTP requests require authentication
-	FW     string `json:"fw" yaml:"fw"`         // Current firmware version
-	LongID int    `json:"longid" yaml:"longid"` // 1 if the device identifies itself with its full MAC address; 0 if only the last 3 bytes are used
+	Type    string `json:"type" yaml:"type"`       // Shelly model identifier
+	MAC     string `json:"mac" yaml:"mac"`         // MAC address of the device
+	Auth    bool   `json:"auth" yaml:"auth"`       // Whether HTTP requests require authentication
+	FW      string `json:"fw" yaml:"fw"`           // Current firmware version
+	LongID  int    `json:"longid" yaml:"longid"`   // 1 if the device identifies itself with its full MAC address; 0 if only the last 3 bytes are used
+	Gen     int    `json:"gen" yaml:"gen"`         // Device generation, only set by Gen 2 and newer devices
+	Model   string `json:"model" yaml:"model"`     // Shelly model identifier on Gen 2 devices
+	Version string `json:"ver" yaml:"ver"`         // Current firmware version on Gen 2 devices
+	AuthEn  bool   `json:"auth_en" yaml:"auth_en"` // Whether HTTP requests require authentication on Gen 2 devices
+	Mode    string `json:"mode" yaml:"mode"`       // Operating mode of Gen 1 devices with multiple modes, relay or roller
+	Profile string `json:"profile" yaml:"profile"` // Device profile on Gen 2 devices with multiple profiles, like switch or cover
+}
+
+// Generation is the device generation, 1 for devices that do not report it
+func (i *DeviceInfo) Generation() int {
+	if i.Gen == 0 {
+		return 1
+	}
+
+	return i.Gen
 }
 
 // WiFiStatus represents the current status of the WiFi connection.
@@ -81,6 +159,95 @@ type Relay struct {
 	Source         string `json:"source" yaml:"source"`                   // Source that caused the last state change
 }
 
+// Light represents the current state of each light output channel of a dimmer.
+type Light struct {
+	IsOn           bool   `json:"ison" yaml:"ison"`                       // Indicates if the light is on
+	HasTimer       bool   `json:"has_timer" yaml:"has_timer"`             // Indicates if a timer is set
+	TimerStarted   int64  `json:"timer_started" yaml:"timer_started"`     // Timestamp when the timer was started
+	TimerDuration  int64  `json:"timer_duration" yaml:"timer_duration"`   // Duration of the timer
+	TimerRemaining int64  `json:"timer_remaining" yaml:"timer_remaining"` // Time remaining on the timer
+	Source         string `json:"source" yaml:"source"`                   // Source that caused the last state change
+	Mode           string `json:"mode" yaml:"mode"`                       // Light mode, white on dimmers
+	Brightness     int    `json:"brightness" yaml:"brightness"`           // Brightness in percent
+	Transition     int    `json:"transition" yaml:"transition"`           // Milliseconds taken to change between states
+}
+
+// Relay is the relay state of the light
+func (l *Light) Relay() *Relay {
+	return &Relay{
+		IsOn:           l.IsOn,
+		HasTimer:       l.HasTimer,
+		TimerStarted:   l.TimerStarted,
+		TimerDuration:  l.TimerDuration,
+		TimerRemaining: l.TimerRemaining,
+		Source:         l.Source,
+	}
+}
+
+// RollerState represents the current state of each roller of a device in roller mode.
+type RollerState struct {
+	State         string  `json:"state" yaml:"state"`                   // Movement state, open, close or stop
+	Source        string  `json:"source" yaml:"source"`                 // Source that caused the last state change
+	Power         float64 `json:"power" yaml:"power"`                   // Current power usage
+	CurrentPos    int     `json:"current_pos" yaml:"current_pos"`       // Position in percent, 100 is fully open
+	LastDirection string  `json:"last_direction" yaml:"last_direction"` // Direction of the last movement, open or close
+	Positioning   bool    `json:"positioning" yaml:"positioning"`       // Whether the roller is calibrated and supports positioning
+}
+
+// Moving determines if the roller is opening or closing
+func (r *RollerState) Moving() bool {
+	return r.State == "open" || r.State == "close"
+}
+
+// SensorTemperature is a temperature reading of a sensor or thermostat.
+type SensorTemperature struct {
+	Value   float64 `json:"value" yaml:"value"`       // Temperature in the configured units
+	Units   string  `json:"units" yaml:"units"`       // Units of the temperature, C or F
+	TC      float64 `json:"tC" yaml:"tC"`             // Temperature in °C, not set by TRVs
+	TF      float64 `json:"tF" yaml:"tF"`             // Temperature in °F, not set by TRVs
+	IsValid bool    `json:"is_valid" yaml:"is_valid"` // Validity of the reading
+}
+
+// Celsius is the temperature in °C
+func (t *SensorTemperature) Celsius() float64 {
+	switch t.Units {
+	case "":
+		// plugs only report the internal temperature in tC and tF
+		return t.TC
+	case "F":
+		return (t.Value - 32) * 5 / 9
+	}
+
+	return t.Value
+}
+
+// SensorHumidity is a relative humidity reading of a sensor.
+type SensorHumidity struct {
+	Value   float64 `json:"value" yaml:"value"`       // Relative humidity in percent
+	IsValid bool    `json:"is_valid" yaml:"is_valid"` // Validity of the reading
+}
+
+// Battery is the state of the battery of a battery powered device.
+type Battery struct {
+	Value   int     `json:"value" yaml:"value"`     // Battery level in percent
+	Voltage float64 `json:"voltage" yaml:"voltage"` // Battery voltage
+}
+
+// Thermostat represents the current state of a TRV thermostat.
+type Thermostat struct {
+	Position float64           `json:"pos" yaml:"pos"`           // Valve position in percent
+	Target   SensorTemperature `json:"target_t" yaml:"target_t"` // Target temperature
+	Tmp      SensorTemperature `json:"tmp" yaml:"tmp"`           // Measured temperature
+}
+
+// SensorStatus is the current reading of a temperature sensor or thermostat.
+type SensorStatus struct {
+	Temperature float64  `json:"temperature_c" yaml:"temperature_c"`           // Temperature in °C
+	Humidity    *float64 `json:"humidity,omitempty" yaml:"humidity,omitempty"` // Relative humidity in percent, only set by H&T sensors
+	Target      *float64 `json:"target_c,omitempty" yaml:"target_c,omitempty"` // Target temperature in °C, only set by thermostats
+	Battery     int      `json:"battery_percent" yaml:"battery_percent"`       // Battery level in percent
+}
+
 // Meter represents the current status of each power meter.
 type Meter struct {
 	Power     float64   `json:"power" yaml:"power"`         // Current power usage
@@ -88,5 +255,103 @@ type Meter struct {
 	IsValid   bool      `json:"is_valid" yaml:"is_valid"`   // Validity of the meter reading
 	Timestamp int64     `json:"timestamp" yaml:"timestamp"` // Timestamp of the meter reading
 	Counters  []float64 `json:"counters" yaml:"counters"`   // Counters array with meter readings
-	Total     int64     `json:"total" yaml:"total"`         // Total consumption
+	Total     int64     `json:"total" yaml:"total"`         // Total consumption in Watt-minutes
+}
+
+// wattMinutesPerKWh is the number of Watt-minutes in a kWh
+const wattMinutesPerKWh = 60000
+
+// TotalKWh is the total consumption in kWh.
+//
+// Gen 1 devices report the total in Watt-minutes and not Wh, see the meters section of the
+// status API at https://shelly-api-docs.shelly.cloud/gen1/#shelly-plug-plugs-status
+func (m *Meter) TotalKWh() float64 {
+	return float64(m.Total) / wattMinutesPerKWh
+}
+
+// Gen2WiFiConfig is the response from the Gen 2 WiFi.GetConfig RPC method
+type Gen2WiFiConfig struct {
+	AP   Gen2WiFiAPConfig      `json:"ap" yaml:"ap"`     // Access point configuration
+	Sta  Gen2WiFiStationConfig `json:"sta" yaml:"sta"`   // Primary station configuration
+	Sta1 Gen2WiFiStationConfig `json:"sta1" yaml:"sta1"` // Fallback station configuration
+}
+
+// Gen2WiFiAPConfig is the access point configuration of a Gen 2 device
+type Gen2WiFiAPConfig struct {
+	SSID   string `json:"ssid" yaml:"ssid"`       // SSID of the access point
+	IsOpen bool   `json:"is_open" yaml:"is_open"` // Whether the access point is open
+	Enable bool   `json:"enable" yaml:"enable"`   // Whether the access point is enabled
+}
+
+// Gen2WiFiStationConfig is the station configuration of a Gen 2 device
+type Gen2WiFiStationConfig struct {
+	SSID     string `json:"ssid" yaml:"ssid"`             // SSID of the network to connect to
+	IsOpen   bool   `json:"is_open" yaml:"is_open"`       // Whether the network is open
+	Enable   bool   `json:"enable" yaml:"enable"`         // Whether the station is enabled
+	IPv4Mode string `json:"ipv4mode" yaml:"ipv4mode"`     // Either dhcp or static
+	IP       string `json:"ip" yaml:"ip"`                 // Static IP address
+	Netmask  string `json:"netmask" yaml:"netmask"`       // Static network mask
+	Gateway  string `json:"gw" yaml:"gw"`                 // Static gateway
+	Resolver string `json:"nameserver" yaml:"nameserver"` // Static name server
+}
+
+// Gen2WiFiStatus is the response from the Gen 2 WiFi.GetStatus RPC method
+type Gen2WiFiStatus struct {
+	StaIP         string `json:"sta_ip" yaml:"sta_ip"`                   // IP address of the device on the network
+	Status        string `json:"status" yaml:"status"`                   // Connection status
+	SSID          string `json:"ssid" yaml:"ssid"`                       // SSID of the connected network
+	RSSI          int    `json:"rssi" yaml:"rssi"`                       // Signal strength indicator
+	APClientCount int    `json:"ap_client_count" yaml:"ap_client_count"` // Number of clients connected to the access point
+}
+
+// Gen2SysConfig is the response from the Gen 2 Sys.GetConfig RPC method
+type Gen2SysConfig struct {
+	Device Gen2SysDeviceConfig `json:"device" yaml:"device"` // Device configuration
+}
+
+// Gen2SysDeviceConfig is the device section of the Gen 2 system configuration
+type Gen2SysDeviceConfig struct {
+	Name         string `json:"name" yaml:"name"`                 // Name of the device
+	EcoMode      bool   `json:"eco_mode" yaml:"eco_mode"`         // Whether eco mode is enabled
+	MAC          string `json:"mac" yaml:"mac"`                   // MAC address of the device
+	FwID         string `json:"fw_id" yaml:"fw_id"`               // Firmware identifier
+	Discoverable bool   `json:"discoverable" yaml:"discoverable"` // Whether the device is discoverable
+}
+
+// Gen2InputConfig is the response from the Gen 2 Input.GetConfig RPC method
+type Gen2InputConfig struct {
+	ID     int    `json:"id" yaml:"id"`         // Input instance
+	Name   string `json:"name" yaml:"name"`     // Name of the input
+	Type   string `json:"type" yaml:"type"`     // Input type, switch, button or analog
+	Invert bool   `json:"invert" yaml:"invert"` // Whether the input state is inverted
+}
+
+// Gen2InputStatus is the response from the Gen 2 Input.GetStatus RPC method
+type Gen2InputStatus struct {
+	ID    int   `json:"id" yaml:"id"`       // Input instance
+	State *bool `json:"state" yaml:"state"` // State of a switch input, not set for buttons
+}
+
+// Gen2SwitchConfig is the response from the Gen 2 Switch.GetConfig RPC method
+type Gen2SwitchConfig struct {
+	ID           int     `json:"id" yaml:"id"`                         // Switch instance
+	Name         string  `json:"name" yaml:"name"`                     // Name of the switch
+	InMode       string  `json:"in_mode" yaml:"in_mode"`               // How the input controls the switch, like follow or detached
+	InitialState string  `json:"initial_state" yaml:"initial_state"`   // State of the switch after power on
+	AutoOn       bool    `json:"auto_on" yaml:"auto_on"`               // Whether the switch turns on automatically
+	AutoOnDelay  float64 `json:"auto_on_delay" yaml:"auto_on_delay"`   // Seconds before the switch turns on automatically
+	AutoOff      bool    `json:"auto_off" yaml:"auto_off"`             // Whether the switch turns off automatically
+	AutoOffDelay float64 `json:"auto_off_delay" yaml:"auto_off_delay"` // Seconds before the switch turns off automatically
+}
+
+// Gen1ActionSettings is the response from the Gen 1 /settings/actions API
+type Gen1ActionSettings struct {
+	Actions map[string][]Gen1Action `json:"actions" yaml:"actions"` // URL actions by name, like out_on_url
+}
+
+// Gen1Action is a URL action called by a Gen 1 device when an event occurs
+type Gen1Action struct {
+	Index   int      `json:"index" yaml:"index"`     // Channel the action applies to
+	Enabled bool     `json:"enabled" yaml:"enabled"` // Whether the action is enabled
+	URLs    []string `json:"urls" yaml:"urls"`       // URLs requested when the event occurs
 }
@@ -0,0 +1,25 @@
+package shellyctl
+
+import (
+	"testing"
+)
+
+func TestMeterTotalKWh(t *testing.T) {
+	cases := []struct {
+		total int64
+		kwh   float64
+	}{
+		{0, 0},
+		{60, 0.001},
+		{60000, 1},
+		{613, 0.010216666666666667},
+		{1500000, 25},
+	}
+
+	for _, c := range cases {
+		m := Meter{Total: c.total}
+		if kwh := m.TotalKWh(); kwh != c.kwh {
+			t.Errorf("expected %d Watt-minutes to be %v kWh got %v", c.total, c.kwh, kwh)
+		}
+	}
+}
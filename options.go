@@ -0,0 +1,89 @@
+package shellyctl
+
+import (
+	"crypto/tls"
+	"io"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Option configures a Plug created using NewPlug
+type Option func(*plugConfig)
+
+type plugConfig struct {
+	timeout    time.Duration
+	tlsConfig  *tls.Config
+	insecure   bool
+	socksProxy string
+	dryRun     io.Writer
+	limiter    *rate.Limiter
+	cacheTTL   time.Duration
+	retries    int
+	retryWait  time.Duration
+	debug      bool
+	user       string
+	pass       string
+	userAgent  string
+}
+
+// WithTimeout sets the timeout for requests to the device, defaults to 10 seconds
+func WithTimeout(d time.Duration) Option {
+	return func(c *plugConfig) { c.timeout = d }
+}
+
+// WithTLSConfig sets the TLS configuration used for HTTPS addresses
+func WithTLSConfig(tlsc *tls.Config) Option {
+	return func(c *plugConfig) { c.tlsConfig = tlsc }
+}
+
+// WithInsecure disables verification of the device certificate for HTTPS addresses
+func WithInsecure(insecure bool) Option {
+	return func(c *plugConfig) { c.insecure = insecure }
+}
+
+// WithSOCKS5Proxy connects to the device through the SOCKS5 proxy at address
+func WithSOCKS5Proxy(address string) Option {
+	return func(c *plugConfig) { c.socksProxy = address }
+}
+
+// WithDryRun writes requests that would change the device to w instead of sending them
+func WithDryRun(w io.Writer) Option {
+	return func(c *plugConfig) { c.dryRun = w }
+}
+
+// WithRateLimiter waits for limiter before every request, a limiter can be shared by many plugs
+func WithRateLimiter(limiter *rate.Limiter) Option {
+	return func(c *plugConfig) { c.limiter = limiter }
+}
+
+// WithCacheTTL caches the device information for d, by default it is requested every time
+func WithCacheTTL(d time.Duration) Option {
+	return func(c *plugConfig) { c.cacheTTL = d }
+}
+
+// WithRetry retries requests that could not reach the device up to count times, waiting wait between attempts
+func WithRetry(count int, wait time.Duration) Option {
+	return func(c *plugConfig) {
+		c.retries = count
+		c.retryWait = wait
+	}
+}
+
+// WithDebug logs every request and response in full
+func WithDebug(debug bool) Option {
+	return func(c *plugConfig) { c.debug = debug }
+}
+
+// WithCredentials authenticates to the device, takes precedence over credentials in the address
+func WithCredentials(user string, pass string) Option {
+	return func(c *plugConfig) {
+		c.user = user
+		c.pass = pass
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request, by default the resty User-Agent is used
+func WithUserAgent(userAgent string) Option {
+	return func(c *plugConfig) { c.userAgent = userAgent }
+}
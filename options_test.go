@@ -0,0 +1,152 @@
+package shellyctl
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func serverURL(srv *httptest.Server) url.URL {
+	u, _ := url.Parse(srv.URL)
+	return *u
+}
+
+func TestWithCredentials(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "admin" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"type":"SHPLG-S"}`))
+	}))
+	defer srv.Close()
+
+	address := serverURL(srv)
+	address.User = url.UserPassword("admin", "wrong")
+
+	plug, err := NewPlug(address)
+	if err != nil {
+		t.Fatalf("could not create plug: %v", err)
+	}
+	_, err = plug.Info(context.Background())
+	if err == nil {
+		t.Fatalf("expected the credentials in the address to be rejected")
+	}
+
+	plug, err = NewPlug(address, WithCredentials("admin", "secret"))
+	if err != nil {
+		t.Fatalf("could not create plug: %v", err)
+	}
+	_, err = plug.Info(context.Background())
+	if err != nil {
+		t.Fatalf("request with credentials failed: %v", err)
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	var requests atomic.Int64
+
+	// the first two connections are closed without a response
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= 2 {
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		w.Write([]byte(`{"type":"SHPLG-S"}`))
+	}))
+	defer srv.Close()
+
+	plug, err := NewPlug(serverURL(srv))
+	if err != nil {
+		t.Fatalf("could not create plug: %v", err)
+	}
+	_, err = plug.Info(context.Background())
+	if err == nil {
+		t.Fatalf("expected the request to fail without retries")
+	}
+
+	requests.Store(0)
+	plug, err = NewPlug(serverURL(srv), WithRetry(2, 10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("could not create plug: %v", err)
+	}
+	_, err = plug.Info(context.Background())
+	if err != nil {
+		t.Fatalf("request with retries failed: %v", err)
+	}
+	if requests.Load() != 3 {
+		t.Fatalf("expected 3 requests got %d", requests.Load())
+	}
+}
+
+func TestWithInsecure(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"type":"SHPLG-S"}`))
+	}))
+	defer srv.Close()
+
+	plug, err := NewPlug(serverURL(srv))
+	if err != nil {
+		t.Fatalf("could not create plug: %v", err)
+	}
+	_, err = plug.Info(context.Background())
+	if err == nil {
+		t.Fatalf("expected the self-signed certificate to be rejected")
+	}
+
+	plug, err = NewPlug(serverURL(srv), WithInsecure(true))
+	if err != nil {
+		t.Fatalf("could not create plug: %v", err)
+	}
+	_, err = plug.Info(context.Background())
+	if err != nil {
+		t.Fatalf("request without verification failed: %v", err)
+	}
+}
+
+func TestWithUserAgent(t *testing.T) {
+	var agent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agent = r.UserAgent()
+		w.Write([]byte(`{"type":"SHPLG-S"}`))
+	}))
+	defer srv.Close()
+
+	plug, err := NewPlug(serverURL(srv), WithUserAgent("shellyctl/v1.2.3"))
+	if err != nil {
+		t.Fatalf("could not create plug: %v", err)
+	}
+	_, err = plug.Info(context.Background())
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+
+	if agent != "shellyctl/v1.2.3" {
+		t.Fatalf("expected the custom User-Agent got %q", agent)
+	}
+}
+
+func TestNewDialer(t *testing.T) {
+	dialer, err := NewDialer(WithTimeout(time.Second))
+	if err != nil {
+		t.Fatalf("could not create dialer: %v", err)
+	}
+	if d, ok := dialer.(*net.Dialer); !ok || d.Timeout != time.Second {
+		t.Fatalf("expected a direct dialer with a 1s timeout got %#v", dialer)
+	}
+
+	dialer, err = NewDialer(WithSOCKS5Proxy("127.0.0.1:1080"))
+	if err != nil {
+		t.Fatalf("could not create proxy dialer: %v", err)
+	}
+	if _, ok := dialer.(*net.Dialer); ok {
+		t.Fatalf("expected a SOCKS5 dialer")
+	}
+}
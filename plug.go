@@ -1,49 +1,149 @@
-package main
+// Package shellyctl is a client for Shelly Gen 1 and Gen 2 smart plugs
+package shellyctl
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
+	"net/http"
 	"net/url"
+	"strings"
+	"time"
 
 	"github.com/go-resty/resty/v2"
 )
 
-func NewShellyPlug(address url.URL) (Plug, error) {
+// NewPlug creates a Plug for the Gen 1 or Gen 2 device at address, credentials are taken from the address
+func NewPlug(address url.URL, opts ...Option) (Plug, error) {
 	if address.Host == "" {
 		return nil, fmt.Errorf("invalid address")
 	}
 
+	cfg := plugConfig{timeout: 10 * time.Second}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	// the client is shared by all requests so connections to the device are reused
+	rc, err := newRestyClient(&address, cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	return &shellyPlug{
 		address: &address,
+		client:  rc,
+		cfg:     cfg,
 	}, nil
 }
 
+// shellyPlug is the Plug implementation for both generations, the relay, status and settings methods use the Gen 1
+// HTTP API while RPC is only supported by Gen 2 devices, DeviceInfo.Generation tells them apart. Dimmers, rollers
+// and sensors embed it and replace the methods that differ for their device type
 type shellyPlug struct {
 	address *url.URL
+	client  *resty.Client
+	cfg     plugConfig
 }
 
-func (s *shellyPlug) get(path string, queries map[string]string, response any) error {
-	rc := resty.New()
-	client := rc.R()
+// endpoint is the URL of path on the device
+func (s *shellyPlug) endpoint(path string) string {
+	// the URL is built so the zone of link local IPv6 addresses like [fe80::1%eth0] is escaped
+	u := url.URL{Scheme: s.address.Scheme, Host: s.address.Host, Path: "/" + path}
 
-	if s.address.User != nil {
-		password, _ := s.address.User.Password()
-		client.SetBasicAuth(s.address.User.Username(), password)
-		rc.SetDisableWarn(true)
+	return u.String()
+}
+
+// get performs a Gen 1 request, requests with queries change the device and are not sent in dry-run mode
+func (s *shellyPlug) get(ctx context.Context, path string, queries map[string]string, response any) error {
+	if s.cfg.dryRun != nil && len(queries) > 0 {
+		q := url.Values{}
+		for k, v := range queries {
+			q.Set(k, v)
+		}
+
+		fmt.Fprintf(s.cfg.dryRun, "DRY RUN: GET %s?%s\n", s.endpoint(path), q.Encode())
+
+		return nil
+	}
+
+	err := waitForRateLimit(ctx, s.cfg.limiter)
+	if err != nil {
+		return err
 	}
 
+	client := s.client.R().SetContext(ctx)
 	client.SetQueryParams(queries)
 
-	resp, err := client.Get(fmt.Sprintf("http://%s/%s", s.address.Hostname(), path))
+	resp, err := client.Get(s.endpoint(path))
+	if err != nil {
+		return requestError(err)
+	}
+
+	return s.parseResponse(resp, response)
+}
+
+// cachedGet performs a Gen 1 request for path, responses are cached for ttl
+func (s *shellyPlug) cachedGet(ctx context.Context, path string, ttl time.Duration, response any) error {
+	key := s.endpoint(path)
+
+	body, ok := responseCache.get(key)
+	if !ok {
+		var raw json.RawMessage
+
+		err := s.get(ctx, path, nil, &raw)
+		if err != nil {
+			return err
+		}
+
+		body = raw
+		responseCache.set(key, body, ttl)
+	}
+
+	return json.Unmarshal(body, response)
+}
+
+func (s *shellyPlug) post(ctx context.Context, path string, body any, response any) error {
+	if s.cfg.dryRun != nil && !readOnlyRPC(path) {
+		j, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(s.cfg.dryRun, "DRY RUN: POST %s %s\n", s.endpoint(path), j)
+
+		return nil
+	}
+
+	err := waitForRateLimit(ctx, s.cfg.limiter)
 	if err != nil {
 		return err
 	}
 
+	client := s.client.R().SetContext(ctx)
+	client.SetBody(body)
+
+	resp, err := client.Post(s.endpoint(path))
+	if err != nil {
+		return requestError(err)
+	}
+
+	return s.parseResponse(resp, response)
+}
+
+func (s *shellyPlug) parseResponse(resp *resty.Response, response any) error {
+	slog.Debug("Received response", "url", resp.Request.URL, "status", resp.StatusCode(), "time", resp.Time(), "body", resp.String())
+
+	if resp.StatusCode() == http.StatusUnauthorized {
+		return fmt.Errorf("%w: %s", ErrAuthFailed, resp.Request.URL)
+	}
+
 	if resp.IsError() {
 		return fmt.Errorf("%s: %s", resp.Request.URL, resp.String())
 	}
 
-	err = json.Unmarshal(resp.Body(), response)
+	err := json.Unmarshal(resp.Body(), response)
 	if err != nil {
 		return fmt.Errorf("invalid response body: %v", err)
 	}
@@ -51,39 +151,94 @@ func (s *shellyPlug) get(path string, queries map[string]string, response any) e
 	return nil
 }
 
-func (s *shellyPlug) TurnOn() (*Relay, error) {
+func (s *shellyPlug) TurnOn(ctx context.Context) (*Relay, error) {
 	var res Relay
 
-	err := s.get("relay/0", map[string]string{"turn": "on"}, &res)
+	err := s.get(ctx, "relay/0", map[string]string{"turn": "on"}, &res)
 	if err != nil {
 		return nil, err
 	}
 
-	if !res.IsOn {
-		return nil, fmt.Errorf("relay is not on")
+	if !res.IsOn && s.cfg.dryRun == nil {
+		return nil, ErrRelayNotOn
 	}
+
+	slog.Info("Relay turned on", "device", s.address.Hostname())
+
 	return &res, nil
 }
 
-func (s *shellyPlug) TurnOff() (*Relay, error) {
+func (s *shellyPlug) TurnOff(ctx context.Context) (*Relay, error) {
 	var res Relay
 
-	err := s.get("relay/0", map[string]string{"turn": "off"}, &res)
+	err := s.get(ctx, "relay/0", map[string]string{"turn": "off"}, &res)
 	if err != nil {
 		return nil, err
 	}
 
-	if res.IsOn {
+	if res.IsOn && s.cfg.dryRun == nil {
 		return &res, fmt.Errorf("relay is on")
 	}
 
+	slog.Info("Relay turned off", "device", s.address.Hostname())
+
+	return &res, nil
+}
+
+func (s *shellyPlug) RelayStatus(ctx context.Context) (*Relay, error) {
+	var res Relay
+
+	err := s.get(ctx, "relay/0", nil, &res)
+	if err != nil {
+		return nil, err
+	}
+
 	return &res, nil
 }
 
-func (s *shellyPlug) Status() (*DeviceStatus, error) {
+func (s *shellyPlug) CancelTimer(ctx context.Context) (*Relay, error) {
+	var res Relay
+
+	err := s.get(ctx, "relay/0", map[string]string{"turn": "off", "timer": "0"}, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.HasTimer {
+		return &res, fmt.Errorf("timer is still active")
+	}
+
+	slog.Info("Relay timer cancelled", "device", s.address.Hostname())
+
+	return &res, nil
+}
+
+func (s *shellyPlug) TurnWithTimer(ctx context.Context, on bool, timer time.Duration) (*Relay, error) {
+	var res Relay
+
+	turn := "off"
+	if on {
+		turn = "on"
+	}
+
+	err := s.get(ctx, "relay/0", map[string]string{"turn": turn, "timer": fmt.Sprint(int(timer.Seconds()))}, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	if !res.HasTimer && s.cfg.dryRun == nil {
+		return &res, fmt.Errorf("timer is not active")
+	}
+
+	slog.Info("Relay timer started", "device", s.address.Hostname(), "turn", turn, "timer", timer)
+
+	return &res, nil
+}
+
+func (s *shellyPlug) Status(ctx context.Context) (*DeviceStatus, error) {
 	var res DeviceStatus
 
-	err := s.get("status", nil, &res)
+	err := s.get(ctx, "status", nil, &res)
 	if err != nil {
 		return nil, err
 	}
@@ -91,13 +246,59 @@ func (s *shellyPlug) Status() (*DeviceStatus, error) {
 	return &res, nil
 }
 
-func (s *shellyPlug) Info() (*DeviceInfo, error) {
+func (s *shellyPlug) Info(ctx context.Context) (*DeviceInfo, error) {
 	var res DeviceInfo
 
-	err := s.get("shelly", nil, &res)
+	err := s.cachedGet(ctx, "shelly", s.cfg.cacheTTL, &res)
 	if err != nil {
 		return nil, err
 	}
 
 	return &res, nil
 }
+
+func (s *shellyPlug) Settings(ctx context.Context) (map[string]any, error) {
+	res := map[string]any{}
+
+	err := s.get(ctx, "settings", nil, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (s *shellyPlug) UpdateSettings(ctx context.Context, component string, settings map[string]string) (map[string]any, error) {
+	res := map[string]any{}
+
+	path := "settings"
+	if component != "" {
+		path = fmt.Sprintf("settings/%s", component)
+	}
+
+	err := s.get(ctx, path, settings, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (s *shellyPlug) Get(ctx context.Context, path string, response any) error {
+	return s.get(ctx, path, nil, response)
+}
+
+// readOnlyRPC determines if the RPC method called using path only retrieves information
+func readOnlyRPC(path string) bool {
+	_, method, _ := strings.Cut(strings.TrimPrefix(path, "rpc/"), ".")
+
+	return strings.HasPrefix(method, "Get") || strings.HasPrefix(method, "List") || strings.HasPrefix(method, "Check")
+}
+
+func (s *shellyPlug) RPC(ctx context.Context, method string, params any, response any) error {
+	if params == nil {
+		params = map[string]any{}
+	}
+
+	return s.post(ctx, fmt.Sprintf("rpc/%s", method), params, response)
+}
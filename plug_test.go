@@ -0,0 +1,98 @@
+package shellyctl
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEndpointIPv6(t *testing.T) {
+	cases := []struct {
+		host     string
+		expected string
+	}{
+		{"192.168.1.10", "http://192.168.1.10/shelly"},
+		{"[fe80::1]", "http://[fe80::1]/shelly"},
+		{"[fe80::1]:8080", "http://[fe80::1]:8080/shelly"},
+		{"[fe80::1%eth0]", "http://[fe80::1%25eth0]/shelly"},
+	}
+
+	for _, c := range cases {
+		plug, err := NewPlug(url.URL{Scheme: "http", Host: c.host})
+		if err != nil {
+			t.Fatalf("could not create plug for %s: %v", c.host, err)
+		}
+
+		endpoint := plug.(*shellyPlug).endpoint("shelly")
+		if endpoint != c.expected {
+			t.Errorf("expected %s got %s", c.expected, endpoint)
+		}
+	}
+}
+
+func TestIPv6Device(t *testing.T) {
+	listener, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 loopback is not available: %v", err)
+	}
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"type":"SHPLG-S"}`))
+	}))
+	srv.Listener.Close()
+	srv.Listener = listener
+	srv.Start()
+	defer srv.Close()
+
+	plug, err := NewPlug(serverURL(srv))
+	if err != nil {
+		t.Fatalf("could not create plug: %v", err)
+	}
+
+	nfo, err := plug.Info(context.Background())
+	if err != nil {
+		t.Fatalf("request to %s failed: %v", srv.URL, err)
+	}
+	if nfo.Type != "SHPLG-S" {
+		t.Fatalf("unexpected device type %s", nfo.Type)
+	}
+}
+
+func TestCertificateError(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"type":"SHPLG-S"}`))
+	}))
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	plug, err := NewPlug(url.URL{Scheme: "https", Host: net.JoinHostPort("localhost", port)}, WithTLSConfig(&tls.Config{RootCAs: roots}))
+	if err != nil {
+		t.Fatalf("could not create plug: %v", err)
+	}
+
+	_, err = plug.Info(context.Background())
+	if !errors.Is(err, ErrCertificateInvalid) || !strings.Contains(err.Error(), "hostname mismatch") {
+		t.Fatalf("expected a hostname mismatch: %v", err)
+	}
+
+	expired := x509.CertificateInvalidError{Cert: &x509.Certificate{NotAfter: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}, Reason: x509.Expired}
+	if reason := certificateError(fmt.Errorf("wrapped: %w", expired)); reason != "certificate expired on 2020-01-01T00:00:00Z" {
+		t.Fatalf("unexpected reason %q", reason)
+	}
+
+	if reason := certificateError(errors.New("connection refused")); reason != "" {
+		t.Fatalf("unexpected reason %q", reason)
+	}
+}
@@ -0,0 +1,16 @@
+package shellyctl
+
+import (
+	"context"
+
+	"golang.org/x/time/rate"
+)
+
+// waitForRateLimit blocks until limiter allows the next request to be sent, nil limiters never block
+func waitForRateLimit(ctx context.Context, limiter *rate.Limiter) error {
+	if limiter == nil {
+		return nil
+	}
+
+	return limiter.Wait(ctx)
+}
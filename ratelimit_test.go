@@ -0,0 +1,50 @@
+package shellyctl
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestWaitForRateLimit(t *testing.T) {
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		err := waitForRateLimit(context.Background(), nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if time.Since(start) > 100*time.Millisecond {
+		t.Fatalf("requests were limited without a limiter")
+	}
+
+	limiter := rate.NewLimiter(20, 1)
+
+	// 10 workers sending 3 requests each at 20 per second takes at least 1.45 seconds
+	start = time.Now()
+	wg := sync.WaitGroup{}
+	for w := 0; w < 10; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 3; i++ {
+				err := waitForRateLimit(context.Background(), limiter)
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	elapsed := time.Since(start)
+	if elapsed < 1400*time.Millisecond {
+		t.Fatalf("30 requests at 20 per second took %v", elapsed)
+	}
+	if elapsed > 3*time.Second {
+		t.Fatalf("30 requests at 20 per second took %v", elapsed)
+	}
+}
@@ -0,0 +1,210 @@
+package shellyctl
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+)
+
+// NewRoller creates a Roller for the Gen 1 or Gen 2 device in roller mode at address, credentials are taken from the address
+func NewRoller(address url.URL, generation int, opts ...Option) (Roller, error) {
+	plug, err := NewPlug(address, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if generation > 1 {
+		return &shellyRollerV2{shellyPlug: plug.(*shellyPlug)}, nil
+	}
+
+	return &shellyRollerV1{shellyPlug: plug.(*shellyPlug)}, nil
+}
+
+// IsRoller determines if the device is a Shelly 2.5 in roller mode or a Gen 2 device using the cover profile
+func (i *DeviceInfo) IsRoller() bool {
+	if i.Generation() > 1 {
+		return i.Profile == "cover"
+	}
+
+	return strings.HasPrefix(i.Type, "SHSW-25") && i.Mode == "roller"
+}
+
+// shellyRollerV1 is a Gen 1 device in roller mode, these are controlled using /roller/0
+type shellyRollerV1 struct {
+	*shellyPlug
+}
+
+func (s *shellyRollerV1) roller(ctx context.Context, queries map[string]string) (*RollerState, error) {
+	var res RollerState
+
+	err := s.get(ctx, "roller/0", queries, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+func (s *shellyRollerV1) Open(ctx context.Context) (*RollerState, error) {
+	res, err := s.roller(ctx, map[string]string{"go": "open"})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("Roller opening", "device", s.address.Hostname())
+
+	return res, nil
+}
+
+func (s *shellyRollerV1) Close(ctx context.Context) (*RollerState, error) {
+	res, err := s.roller(ctx, map[string]string{"go": "close"})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("Roller closing", "device", s.address.Hostname())
+
+	return res, nil
+}
+
+func (s *shellyRollerV1) Stop(ctx context.Context) (*RollerState, error) {
+	res, err := s.roller(ctx, map[string]string{"go": "stop"})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("Roller stopped", "device", s.address.Hostname())
+
+	return res, nil
+}
+
+func (s *shellyRollerV1) SetPosition(ctx context.Context, position int) (*RollerState, error) {
+	if position < 0 || position > 100 {
+		return nil, fmt.Errorf("position must be between 0 and 100")
+	}
+
+	res, err := s.roller(ctx, map[string]string{"go": "to_pos", "roller_pos": fmt.Sprint(position)})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("Roller moving to position", "device", s.address.Hostname(), "position", position)
+
+	return res, nil
+}
+
+func (s *shellyRollerV1) RollerStatus(ctx context.Context) (*RollerState, error) {
+	return s.roller(ctx, nil)
+}
+
+// shellyRollerV2 is a Gen 2 device using the cover profile, these are controlled using the Cover RPC methods
+type shellyRollerV2 struct {
+	*shellyPlug
+}
+
+// gen2CoverStatus is the response from the Gen 2 Cover.GetStatus RPC method
+type gen2CoverStatus struct {
+	State         string  `json:"state"`
+	Source        string  `json:"source"`
+	APower        float64 `json:"apower"`
+	CurrentPos    int     `json:"current_pos"`
+	LastDirection string  `json:"last_direction"`
+	PosControl    bool    `json:"pos_control"`
+}
+
+// cover calls a Cover RPC method, the status is retrieved afterwards as the methods that move the cover do not return it
+func (s *shellyRollerV2) cover(ctx context.Context, method string, params map[string]any) (*RollerState, error) {
+	if params == nil {
+		params = map[string]any{}
+	}
+	params["id"] = 0
+
+	if method != "GetStatus" {
+		err := s.RPC(ctx, "Cover."+method, params, &map[string]any{})
+		if err != nil {
+			return nil, err
+		}
+
+		if s.cfg.dryRun != nil {
+			return &RollerState{}, nil
+		}
+	}
+
+	var res gen2CoverStatus
+	err := s.RPC(ctx, "Cover.GetStatus", map[string]any{"id": 0}, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	// Gen 2 devices report open and closed once the cover stopped, these are shown as stop like on Gen 1
+	state := "stop"
+	switch res.State {
+	case "opening":
+		state = "open"
+	case "closing":
+		state = "close"
+	}
+
+	return &RollerState{
+		State:         state,
+		Source:        res.Source,
+		Power:         res.APower,
+		CurrentPos:    res.CurrentPos,
+		LastDirection: res.LastDirection,
+		Positioning:   res.PosControl,
+	}, nil
+}
+
+func (s *shellyRollerV2) Open(ctx context.Context) (*RollerState, error) {
+	res, err := s.cover(ctx, "Open", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("Roller opening", "device", s.address.Hostname())
+
+	return res, nil
+}
+
+func (s *shellyRollerV2) Close(ctx context.Context) (*RollerState, error) {
+	res, err := s.cover(ctx, "Close", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("Roller closing", "device", s.address.Hostname())
+
+	return res, nil
+}
+
+func (s *shellyRollerV2) Stop(ctx context.Context) (*RollerState, error) {
+	res, err := s.cover(ctx, "Stop", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("Roller stopped", "device", s.address.Hostname())
+
+	return res, nil
+}
+
+func (s *shellyRollerV2) SetPosition(ctx context.Context, position int) (*RollerState, error) {
+	if position < 0 || position > 100 {
+		return nil, fmt.Errorf("position must be between 0 and 100")
+	}
+
+	res, err := s.cover(ctx, "GoToPosition", map[string]any{"pos": position})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("Roller moving to position", "device", s.address.Hostname(), "position", position)
+
+	return res, nil
+}
+
+func (s *shellyRollerV2) RollerStatus(ctx context.Context) (*RollerState, error) {
+	return s.cover(ctx, "GetStatus", nil)
+}
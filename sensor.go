@@ -0,0 +1,64 @@
+package shellyctl
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// NewSensor creates a Sensor for the Gen 1 Shelly H&T or TRV at address, credentials are taken from the address
+func NewSensor(address url.URL, opts ...Option) (Sensor, error) {
+	plug, err := NewPlug(address, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &shellyHT{shellyPlug: plug.(*shellyPlug)}, nil
+}
+
+// IsSensor determines if the device is a Gen 1 Shelly H&T sensor or TRV thermostat
+func (i *DeviceInfo) IsSensor() bool {
+	return strings.HasPrefix(i.Type, "SHHT-") || strings.HasPrefix(i.Type, "SHTRV-")
+}
+
+// shellyHT is a Gen 1 Shelly H&T or TRV, these have no relay and report their readings in /status
+type shellyHT struct {
+	*shellyPlug
+}
+
+func (s *shellyHT) TurnOn(context.Context) (*Relay, error)      { return nil, ErrUnsupported }
+func (s *shellyHT) TurnOff(context.Context) (*Relay, error)     { return nil, ErrUnsupported }
+func (s *shellyHT) RelayStatus(context.Context) (*Relay, error) { return nil, ErrUnsupported }
+func (s *shellyHT) CancelTimer(context.Context) (*Relay, error) { return nil, ErrUnsupported }
+func (s *shellyHT) TurnWithTimer(context.Context, bool, time.Duration) (*Relay, error) {
+	return nil, ErrUnsupported
+}
+
+func (s *shellyHT) SensorStatus(ctx context.Context) (*SensorStatus, error) {
+	status, err := s.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &SensorStatus{}
+
+	switch {
+	case status.Tmp != nil:
+		res.Temperature = status.Tmp.Celsius()
+	case len(status.Thermostats) > 0:
+		res.Temperature = status.Thermostats[0].Tmp.Celsius()
+		target := status.Thermostats[0].Target.Celsius()
+		res.Target = &target
+	}
+
+	if status.Hum != nil {
+		res.Humidity = &status.Hum.Value
+	}
+
+	if status.Bat != nil {
+		res.Battery = status.Bat.Value
+	}
+
+	return res, nil
+}